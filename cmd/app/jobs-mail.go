@@ -1,34 +1,31 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"github.com/aymerick/douceur/inliner"
-	mail "github.com/xhit/go-simple-mail/v2"
-	"html/template"
-	"jaytaylor.com/html2text"
 	"server_monitor/internal/channeldata"
-	"strconv"
+	"server_monitor/internal/mailer"
+	"server_monitor/internal/models"
+	"sync"
 	"time"
 )
 
-type TemplateData struct {
-	Content       template.HTML
-	From          string
-	FromName      string
-	PreferenceMap map[string]string
-	IntMap        map[string]int
-	StringMap     map[string]string
-	FloatMap      map[string]float32
-	RowSets       map[string]interface{}
-}
+const (
+	// maxMailAttempts is how many times a mail is tried before it is marked failed
+	maxMailAttempts = 8
+	// mailRetryBaseDelay is the wait before the first retry; it doubles with each attempt
+	mailRetryBaseDelay = 30 * time.Second
+	mailRetryMaxDelay  = time.Hour
+	// mailPollInterval is how often the queue is checked for mail due another attempt
+	mailPollInterval = 15 * time.Second
+)
 
-func NewTemplateData(mailMessage channeldata.MailData) *TemplateData {
-	return &TemplateData{
+func NewTemplateData(mailMessage channeldata.MailData) mailer.TemplateData {
+	return mailer.TemplateData{
 		Content:       mailMessage.Content,
 		From:          mailMessage.FromAddress,
 		FromName:      mailMessage.FromName,
-		PreferenceMap: preferenceMap,
+		PreferenceMap: app.Preferences.Snapshot(),
 		IntMap:        mailMessage.IntMap,
 		StringMap:     mailMessage.StringMap,
 		FloatMap:      mailMessage.FloatMap,
@@ -36,176 +33,157 @@ func NewTemplateData(mailMessage channeldata.MailData) *TemplateData {
 	}
 }
 
-func (td *TemplateData) toString(tmpl *template.Template) string {
-	var buff bytes.Buffer
-	if err := tmpl.Execute(&buff, td); err != nil {
-		fmt.Println(err)
-	}
-
-	return buff.String()
-}
-
 type Worker struct {
 	id         int
 	jobQueue   chan channeldata.MailJob
 	workerPool chan chan channeldata.MailJob
 	quitChan   chan bool
+	// done is closed when the worker has stopped
+	done chan bool
+	// conn is kept open between jobs, so a burst of mail doesn't connect once per message
+	conn *mailer.Connection
 }
 
-func NewWorker(id int, workerPool chan chan channeldata.MailJob) Worker {
-	return Worker{
+func NewWorker(id int, workerPool chan chan channeldata.MailJob) *Worker {
+	return &Worker{
 		id:         id,
 		jobQueue:   make(chan channeldata.MailJob),
 		workerPool: workerPool,
 		quitChan:   make(chan bool),
+		done:       make(chan bool),
+		conn:       &mailer.Connection{},
 	}
 }
 
-func (w Worker) start() {
+func (w *Worker) start() {
 	go func() {
+		defer close(w.done)
+		defer w.conn.Close()
+
 		for {
 			w.workerPool <- w.jobQueue
 
 			select {
 			case job := <-w.jobQueue:
-				w.processMailQueueJob(job.MailMessage)
+				w.processMailQueueJob(job)
 			case <-w.quitChan:
 				fmt.Printf("worker%d stopping\n", w.id)
+				return
 			}
 		}
 	}()
 }
 
-func (w Worker) stop() {
+// stop tells the worker to stop once it has finished the job it is sending
+func (w *Worker) stop() {
 	go func() {
 		w.quitChan <- true
 	}()
 }
 
-func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
-	tmpl, err := getTemplateString(mailMessage)
+// processMailQueueJob sends a mail job, and records the outcome if the job is in the mail queue
+func (w *Worker) processMailQueueJob(job channeldata.MailJob) {
+	err := w.sendMail(job.MailMessage)
 	if err != nil {
 		fmt.Println(err)
-		return
+	} else {
+		fmt.Println("Email sent!")
 	}
 
-	alternativeText := getAlternativeText(tmpl)
-	formattedMessage := formatTemplateString(tmpl)
+	if job.ID > 0 {
+		recordMailResult(job.ID, err)
+	}
+}
 
-	smtpClient, err := newSMTPClient()
+func (w *Worker) sendMail(mailMessage channeldata.MailData) error {
+	tmpl, err := app.TemplateCache.Get(mailMessage.Template)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
-	email := newSMTPEmail(mailMessage, formattedMessage, alternativeText)
-
-	if err = email.Send(smtpClient); err != nil {
-		fmt.Println(err)
-	} else {
-		fmt.Println("Email sent!")
+	formattedMessage, alternativeText, err := mailer.Render(tmpl, NewTemplateData(mailMessage))
+	if err != nil {
+		return err
 	}
-}
 
-func getTemplate(tmplString string) (*template.Template, error) {
-	if tmplString == "" {
-		tmplString = "bootstrap.mail.tmpl"
-	}
+	email := mailer.NewEmail(mailMessage, formattedMessage, alternativeText)
 
-	tmpl, ok := app.TemplateCache[tmplString]
-	if !ok {
-		return nil, fmt.Errorf("could not get mail template %s", tmplString)
-	}
-	return tmpl, nil
+	return w.conn.Send(mailer.SMTPSettingsFromPreferences(app.Preferences.Snapshot()), email)
 }
 
-func getTemplateString(mailMessage channeldata.MailData) (string, error) {
-	tmpl, err := getTemplate(mailMessage.Template)
+// storeMailJob adds a new mail job to the mail queue with a status, and returns its id, or 0 if it couldn't be stored.
+// Mail left being sent when the app stops is requeued by RequeueSendingMail on the next start.
+func storeMailJob(mailMessage channeldata.MailData, status string) int {
+	id, err := repo.DB.InsertQueuedMail(models.QueuedMail{
+		Status:  status,
+		Message: mailMessage,
+	})
 	if err != nil {
-		return "", err
+		fmt.Println("Could not store mail job:", err)
+		return 0
 	}
-	tmplData := NewTemplateData(mailMessage)
 
-	return tmplData.toString(tmpl), nil
+	return id
 }
 
-func formatTemplateString(tmpl string) string {
-	formattedString, err := inliner.Inline(tmpl)
+// recordMailResult marks a queued mail sent, or schedules another attempt until it has failed maxMailAttempts times
+func recordMailResult(id int, sendErr error) {
+	m, err := repo.DB.GetQueuedMailByID(id)
 	if err != nil {
 		fmt.Println(err)
-		formattedString = tmpl
+		return
 	}
-	return formattedString
-}
 
-func getAlternativeText(tmpl string) string {
-	alternativeText, err := html2text.FromString(tmpl, html2text.Options{PrettyTables: true})
-
-	if err != nil {
-		return ""
+	m.Attempts++
+
+	switch {
+	case sendErr == nil:
+		m.Status = models.MailSent
+		m.SentAt = time.Now()
+		m.LastError = ""
+	case m.Attempts >= maxMailAttempts:
+		m.Status = models.MailFailed
+		m.LastError = sendErr.Error()
+	default:
+		m.Status = models.MailQueued
+		m.LastError = sendErr.Error()
+		m.NextAttemptAt = time.Now().Add(mailRetryDelay(m.Attempts))
 	}
-	return alternativeText
-}
-
-func newSMTPServer() *mail.SMTPServer {
-	server := mail.NewSMTPClient()
 
-	port, _ := strconv.Atoi(preferenceMap["smtp_port"])
-	server.Host = preferenceMap["smtp_server"]
-	server.Port = port
-	server.Username = preferenceMap["smtp_user"]
-	server.Password = preferenceMap["smtp_password"]
-
-	if preferenceMap["smtp_server"] == "localhost" {
-		server.Authentication = mail.AuthPlain
-	} else {
-		server.Authentication = mail.AuthLogin
+	if err = repo.DB.UpdateQueuedMail(m); err != nil {
+		fmt.Println(err)
 	}
-	server.Encryption = mail.EncryptionTLS
-	server.KeepAlive = false
-	server.ConnectTimeout = 10 * time.Second
-	server.SendTimeout = 10 * time.Second
-
-	return server
-}
-
-func newSMTPClient() (*mail.SMTPClient, error) {
-	server := newSMTPServer()
-
-	return server.Connect()
 }
 
-func newSMTPEmail(mailMessage channeldata.MailData, formattedMessage, alternativeText string) *mail.Email {
-	email := mail.NewMSG()
-	email.SetFrom(mailMessage.FromAddress).
-		AddTo(mailMessage.ToAddress).
-		SetSubject(mailMessage.Subject)
-
-	if len(mailMessage.AdditionalTo) > 0 {
-		for _, x := range mailMessage.AdditionalTo {
-			email.AddTo(x)
-		}
-	}
-	if len(mailMessage.CC) > 0 {
-		for _, x := range mailMessage.CC {
-			email.AddCc(x)
-		}
+// mailRetryDelay is the exponential backoff before the next attempt, after attempts tries
+func mailRetryDelay(attempts int) time.Duration {
+	delay := mailRetryBaseDelay
+	for i := 1; i < attempts && delay < mailRetryMaxDelay; i++ {
+		delay *= 2
 	}
-	if len(mailMessage.Attachments) > 0 {
-		for _, x := range mailMessage.Attachments {
-			email.AddAttachment(x)
-		}
+
+	if delay > mailRetryMaxDelay {
+		delay = mailRetryMaxDelay
 	}
-	email.SetBody(mail.TextHTML, formattedMessage)
-	email.AddAlternative(mail.TextPlain, alternativeText)
 
-	return email
+	return delay
 }
 
 type Dispatcher struct {
 	workerPool chan chan channeldata.MailJob
 	maxWorkers int
 	jobQueue   chan channeldata.MailJob
+	workers    []*Worker
+	// stopMu guards stopped; Add holds it for reading while it sends on the job queue,
+	// so stop can't close the queue under it
+	stopMu  sync.RWMutex
+	stopped bool
+	// handoffs counts jobs waiting for a free worker
+	handoffs sync.WaitGroup
+	// dispatched is closed when the job queue is closed and every job is with a worker
+	dispatched chan bool
+	stopPoll   chan bool
+	pollDone   chan bool
 }
 
 func NewDispatcher(jobQueue chan channeldata.MailJob, maxWorkers int) *Dispatcher {
@@ -213,6 +191,9 @@ func NewDispatcher(jobQueue chan channeldata.MailJob, maxWorkers int) *Dispatche
 		workerPool: make(chan chan channeldata.MailJob, maxWorkers),
 		maxWorkers: maxWorkers,
 		jobQueue:   jobQueue,
+		dispatched: make(chan bool),
+		stopPoll:   make(chan bool),
+		pollDone:   make(chan bool),
 	}
 }
 
@@ -220,18 +201,103 @@ func (d *Dispatcher) run() {
 	for i := 0; i < d.maxWorkers; i++ {
 		worker := NewWorker(i+1, d.workerPool)
 		worker.start()
+		d.workers = append(d.workers, worker)
 	}
 	go d.dispatch()
+	go d.poll()
+}
+
+// stop stops polling, closes the job queue, waits until the mail already queued is sent and stops the workers.
+// Mail added after stop is called is only stored. If ctx ends first it returns ctx.Err(),
+// and mail that wasn't sent is picked up again by RequeueSendingMail on the next start.
+func (d *Dispatcher) stop(ctx context.Context) error {
+	close(d.stopPoll)
+	<-d.pollDone
+
+	d.stopMu.Lock()
+	d.stopped = true
+	close(d.jobQueue)
+	d.stopMu.Unlock()
+
+	select {
+	case <-d.dispatched:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, w := range d.workers {
+		w.stop()
+	}
+
+	for _, w := range d.workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Add stores mail in the mail queue and hands it to the workers. The row is written before the handoff,
+// so the mail isn't lost if the app stops before a worker has sent it. Once the dispatcher is stopped,
+// for example by a check still running at the shutdown deadline, the mail is stored as queued and
+// sent after the next start.
+func (d *Dispatcher) Add(mailMessage channeldata.MailData) {
+	d.stopMu.RLock()
+	defer d.stopMu.RUnlock()
+
+	if d.stopped {
+		storeMailJob(mailMessage, models.MailQueued)
+		return
+	}
+
+	d.jobQueue <- channeldata.MailJob{ID: storeMailJob(mailMessage, models.MailSending), MailMessage: mailMessage}
 }
 
 func (d *Dispatcher) dispatch() {
+	defer close(d.dispatched)
+
+	for job := range d.jobQueue {
+		d.handoffs.Add(1)
+		go func(job channeldata.MailJob) {
+			defer d.handoffs.Done()
+			workerJobQueue := <-d.workerPool
+			workerJobQueue <- job
+		}(job)
+	}
+
+	d.handoffs.Wait()
+}
+
+// poll hands mail that is due another attempt to the workers, until stopPoll is closed
+func (d *Dispatcher) poll() {
+	defer close(d.pollDone)
+
+	ticker := time.NewTicker(mailPollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case job := <-d.jobQueue:
-			go func() {
-				workerJobQueue := <-d.workerPool
-				workerJobQueue <- job
-			}()
+		case <-d.stopPoll:
+			return
+		case <-ticker.C:
+		}
+
+		due, err := repo.DB.ClaimDueMail(d.maxWorkers)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		for _, m := range due {
+			select {
+			case d.jobQueue <- channeldata.MailJob{ID: m.ID, MailMessage: m.Message}:
+			case <-d.stopPoll:
+				// claimed mail left behind is requeued on the next start
+				return
+			}
 		}
 	}
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"server_monitor/internal/channeldata"
+	"server_monitor/internal/config"
+	"server_monitor/internal/driver"
+	"server_monitor/internal/handlers"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/mailer"
+	"server_monitor/internal/models"
+	"server_monitor/internal/preferences"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupMailTest points the app at a new sqlite database and the embedded mail templates,
+// with mail going to a port nothing listens on
+func setupMailTest(t *testing.T) {
+	t.Helper()
+
+	db, err := driver.ConnectSqlite(filepath.Join(t.TempDir(), "observer.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.SQL.Close() })
+
+	repo = handlers.NewSqliteHandlers(db, &app)
+	if err = migrateDatabase(db, repo.DB, "admin@example.com", "Secret123!"); err != nil {
+		t.Fatal(err)
+	}
+
+	templateCache, err := mailer.NewTemplateCache("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app = config.AppConfig{DB: db, TemplateCache: templateCache}
+	app.Preferences = preferences.NewService(repo.DB)
+	if err = app.Preferences.Load(); err != nil {
+		t.Fatal(err)
+	}
+	helpers.NewHelpers(&app)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	err = app.Preferences.SetMany(map[string]string{
+		"smtp_server":     "127.0.0.1",
+		"smtp_port":       strconv.Itoa(port),
+		"smtp_timeout":    "1s",
+		"smtp_from_email": "observer@example.com",
+	}, preferences.System)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSendEmailWhileSavingPreferences saves preferences while mail is added and sent, for go test -race
+func TestSendEmailWhileSavingPreferences(t *testing.T) {
+	setupMailTest(t)
+
+	d := NewDispatcher(make(chan channeldata.MailJob, maxWorkerPoolSize), 3)
+	d.run()
+	app.MailQueue = d
+
+	const senders, mailsPerSender = 4, 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < mailsPerSender; j++ {
+				helpers.SendEmail(channeldata.MailData{
+					ToAddress: "admin@example.com",
+					Subject:   fmt.Sprintf("alert %d.%d", i, j),
+					Content:   "Web on host1 is problem",
+					Template:  "alert.mail.tmpl",
+				})
+			}
+		}(i)
+	}
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < mailsPerSender; j++ {
+				err := app.Preferences.SetMany(map[string]string{
+					"smtp_from_name": fmt.Sprintf("Observer %d.%d", i, j),
+					"smtp_timeout":   fmt.Sprintf("%dms", 500+j),
+				}, preferences.System)
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			_ = app.Preferences.Snapshot()
+			_ = app.Preferences.GetDuration("smtp_timeout")
+		}
+	}()
+
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := d.stop(ctx); err != nil {
+		t.Fatalf("stop: %s", err)
+	}
+
+	// every mail was stored, tried once and queued for another attempt, since nothing listens on the port
+	queued, total, err := repo.DB.GetQueuedMails(models.MailQueued, 1, senders*mailsPerSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != senders*mailsPerSender {
+		t.Errorf("%d mails queued, want %d", total, senders*mailsPerSender)
+	}
+	for _, m := range queued {
+		if m.Attempts != 1 || m.LastError == "" {
+			t.Errorf("mail %d has %d attempts and error %q, want one failed attempt", m.ID, m.Attempts, m.LastError)
+		}
+	}
+}
@@ -7,18 +7,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
 	"server_monitor/internal/config"
 	"server_monitor/internal/handlers"
 	"server_monitor/internal/models"
+	"syscall"
 	"time"
 )
 
-var preferenceMap map[string]string
 var app config.AppConfig
 var session *scs.SessionManager
 var wsClient pusher.Client
 var repo *handlers.DBRepo
+var dispatcher *Dispatcher
+var stopWatchingPreferences func()
 
 const observerVersion = "1.0.0"
 const maxWorkerPoolSize = 5
@@ -35,9 +38,6 @@ func main() {
 		log.Fatal(err)
 	}
 
-	defer close(app.MailQueue)
-	defer app.DB.SQL.Close()
-
 	log.Printf("******************************************")
 	log.Printf("** %sVigilate%s v%s built in %s", "\033[31m", "\033[0m", observerVersion, runtime.Version())
 	log.Printf("**----------------------------------------")
@@ -54,9 +54,18 @@ func main() {
 		WriteTimeout:      5 * time.Second,
 	}
 
-	log.Printf("Starting HTTP server on port %s....", insecurePort)
+	go func() {
+		log.Printf("Starting HTTP server on port %s....", insecurePort)
 
-	if err = srv.ListenAndServe(); err != nil {
-		log.Fatal(err)
-	}
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+
+	log.Printf("Received %s, shutting down....", sig)
+	shutdown(srv)
 }
@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 	"github.com/justinas/nosurf"
+	"log"
 	"net/http"
+	"server_monitor/internal/handlers"
 	"server_monitor/internal/helpers"
-	"strconv"
-	"strings"
+	"server_monitor/internal/models"
+	"server_monitor/internal/remember"
 	"time"
 )
 
@@ -23,11 +25,55 @@ func Auth(next http.Handler) http.Handler {
 			http.Redirect(w, r, fmt.Sprintf("/?target=%s", url), http.StatusFound)
 			return
 		}
+
+		// reload the user, so a change to their access level or a deactivation takes effect right away
+		sessionUser, _ := session.Get(r.Context(), "user").(models.User)
+		user, err := repo.SessionUser(r)
+		switch {
+		case err == handlers.ErrUserInactive || err == models.ErrNoRecord:
+			_ = session.Destroy(r.Context())
+			_ = session.RenewToken(r.Context())
+			session.Put(r.Context(), "error", "Your account is inactive")
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		case err == handlers.ErrSignedOut:
+			_ = session.Destroy(r.Context())
+			_ = session.RenewToken(r.Context())
+			session.Put(r.Context(), "error", "You've been signed out, please log in again")
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		case err != nil:
+			helpers.ServerError(w, r, err)
+			return
+		}
+		if user.AccessLevel != sessionUser.AccessLevel || user.Name != sessionUser.Name || user.Email != sessionUser.Email {
+			session.Put(r.Context(), "user", user)
+		}
+
 		w.Header().Add("Cache-Control", "no-store")
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequireAccess only lets users with at least the access level through; it must come after Auth
+func RequireAccess(level int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := session.Get(r.Context(), "user").(models.User)
+			if !user.HasAccess(level) {
+				if r.Method != http.MethodGet {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+				session.Put(r.Context(), "error", "You don't have permission to see that page")
+				http.Redirect(w, r, "/admin/overview", http.StatusSeeOther)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RecoverPanic recovers from a panic
 func RecoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -58,74 +104,80 @@ func NoSurf(next http.Handler) http.Handler {
 	return csrfHandler
 }
 
-// CheckRemember checks to see if we should log the user in automatically
+// CheckRemember logs the user in from their remember me cookie, if they aren't logged in
 func CheckRemember(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", preferenceMap["identifier"]))
-		if err == nil {
-			key := cookie.Value
-			if len(key) > 0 {
-				id, hash := getIdAndHashFromKey(key)
-				isValidHash := repo.DB.CheckForToken(id, hash)
-				isAuthenticated := helpers.IsAuthenticated(r)
-				if !isAuthenticated && isValidHash {
-					// valid remember me token, so log the user in
-					putSessionDataWithValidToken(r, id)
-				} else if !isValidHash {
-					// invalid token, so delete the cookie
-					deleteRememberCookie(w, r)
-					putSessionDataWithInvalidToken(r)
-				}
+		if value := helpers.RememberCookie(r); value != "" && !helpers.IsAuthenticated(r) {
+			if !loginWithRememberToken(w, r, value) {
+				helpers.DeleteRememberCookie(w)
 			}
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// getIdAndHashFromKey extracts user_id and token from key
-func getIdAndHashFromKey(key string) (int, string) {
-	split := strings.Split(key, "|")
-	uid, hash := split[0], split[1]
-	id, _ := strconv.Atoi(uid)
-	return id, hash
-}
+// loginWithRememberToken logs the user in if the cookie value holds a valid token, and rotates the token.
+// It returns false if the cookie should be deleted.
+func loginWithRememberToken(w http.ResponseWriter, r *http.Request, value string) bool {
+	selector, validator, ok := remember.Parse(value)
+	if !ok {
+		return false
+	}
 
-// putSessionDataWithValidToken puts key-value data to the session
-func putSessionDataWithValidToken(r *http.Request, id int) {
-	_ = session.RenewToken(r.Context())
-	user, _ := repo.DB.GetUserById(id)
-	hashedPassword := user.Password
-	session.Put(r.Context(), "userID", id)
-	session.Put(r.Context(), "userName", user.Name)
-	session.Put(r.Context(), "hashedPassword", string(hashedPassword))
-	session.Put(r.Context(), "user", user)
-}
+	token, err := repo.DB.GetRememberToken(selector)
+	if err != nil {
+		return false
+	}
 
-// putSessionDataWithInvalidToken puts key(error)-value data to the session
-func putSessionDataWithInvalidToken(r *http.Request) {
-	session.Put(r.Context(), "error", "You've been logged out from another device!")
-}
+	now := time.Now()
+	rotate := true
+	if !remember.Valid(token, validator, now) {
+		if remember.ValidPrevious(token, validator, now) {
+			// another request sent with the same cookie rotated the token a moment ago, and its
+			// response has the new cookie
+			rotate = false
+		} else {
+			if now.Before(token.ExpiresAt) {
+				// the selector is right but the validator isn't, so the cookie may have been copied
+				log.Printf("Remember me token for user %d did not match, deleting it", token.UserID)
+			}
+			_ = repo.DB.DeleteRememberToken(selector)
+			return false
+		}
+	}
 
-// deleteRememberCookie deletes the remember me cookie, and logs the user out
-func deleteRememberCookie(w http.ResponseWriter, r *http.Request) {
-	_ = session.RenewToken(r.Context())
+	user, err := repo.DB.GetUserById(token.UserID)
+	if err != nil || user.UserActive == 0 {
+		_ = repo.DB.DeleteRememberToken(selector)
+		return false
+	}
 
-	// delete the cookie
-	newCookie := http.Cookie{
-		Name:     fmt.Sprintf("_%s_ggowatcher_remember", preferenceMap["identifier"]),
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-100 * time.Hour),
-		HttpOnly: true,
-		Domain:   app.Domain,
-		MaxAge:   -1,
-		Secure:   app.InProduction,
-		SameSite: http.SameSiteStrictMode,
+	if rotate {
+		rotated, newValue, err := remember.Rotate(token, now)
+		if err != nil {
+			log.Println(err)
+			return false
+		}
+
+		err = repo.DB.UpdateRememberToken(rotated, token.ValidatorHash)
+		if err == models.ErrNoRecord {
+			// another request rotated it first, and has the new cookie; this one is let in
+			// if the token is still there
+			current, err := repo.DB.GetRememberToken(selector)
+			if err != nil || !remember.ValidPrevious(current, validator, now) {
+				return false
+			}
+		} else if err != nil {
+			return false
+		} else {
+			helpers.SetRememberCookie(w, newValue)
+		}
 	}
-	http.SetCookie(w, &newCookie)
 
-	// log them out
-	session.Remove(r.Context(), "userID")
-	_ = session.Destroy(r.Context())
 	_ = session.RenewToken(r.Context())
+	session.Put(r.Context(), "userID", user.ID)
+	session.Put(r.Context(), "loggedInAt", now.UnixNano())
+	session.Put(r.Context(), "user", user)
+
+	return true
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/driver"
+	"server_monitor/internal/migrations"
+	"server_monitor/internal/models"
+	"server_monitor/internal/preferences"
+	"server_monitor/internal/repository"
+	"text/tabwriter"
+)
+
+// runMigrateCommand runs app migrate up|down|status, and exits
+func runMigrateCommand(db *driver.DB, dbRepo repository.DatabaseRepo, args []string, adminEmail, adminPassword string) {
+	migrator, err := migrations.New(db.SQL, db.Type)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+
+		if err = seedDatabase(dbRepo, adminEmail, adminPassword); err != nil {
+			log.Fatal("Cannot seed database:", err)
+		}
+
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if reverted == nil {
+			log.Println("No migrations to revert")
+		} else {
+			log.Printf("Reverted migration %d_%s", reverted.Version, reverted.Name)
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		_ = tw.Flush()
+
+	default:
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+
+	_ = db.SQL.Close()
+	os.Exit(0)
+}
+
+// migrateDatabase applies any pending migrations and seeds the database, when the app starts
+func migrateDatabase(db *driver.DB, dbRepo repository.DatabaseRepo, adminEmail, adminPassword string) error {
+	migrator, err := migrations.New(db.SQL, db.Type)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up()
+	for _, m := range applied {
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	return seedDatabase(dbRepo, adminEmail, adminPassword)
+}
+
+// defaultServices are the services a new database starts with, one for each check type
+var defaultServices = []models.Service{
+	{ServiceName: "HTTP", Icon: "fas fa-server", CheckType: checks.TypeHTTP, Active: 1},
+	{ServiceName: "HTTPS", Icon: "fas fa-lock", CheckType: checks.TypeHTTPS, Active: 1},
+	{ServiceName: "TLS Certificate", Icon: "fas fa-certificate", CheckType: checks.TypeTLS, Active: 1},
+	{ServiceName: "TCP Port", Icon: "fas fa-network-wired", CheckType: checks.TypeTCP, Active: 1},
+}
+
+// seedDatabase creates the first admin user if there are no users, adds any default services that have never
+// existed, and sets any default preferences that are missing. Without an admin password a random one is made
+// up and printed once to stderr.
+func seedDatabase(dbRepo repository.DatabaseRepo, adminEmail, adminPassword string) error {
+	users, err := dbRepo.AllUsers()
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		generated := adminPassword == ""
+		if generated {
+			b := make([]byte, 12)
+			if _, err = rand.Read(b); err != nil {
+				return err
+			}
+			adminPassword = base64.RawURLEncoding.EncodeToString(b)
+		}
+
+		_, err = dbRepo.InsertUser(models.User{
+			Name:        "Admin",
+			Email:       adminEmail,
+			Password:    []byte(adminPassword),
+			AccessLevel: models.AccessAdmin,
+			UserActive:  1,
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Created admin user %s", adminEmail)
+		if generated {
+			// written straight to stderr, not through log, so it is shown once and not kept with the log
+			fmt.Fprintf(os.Stderr, "\nThe admin password is %s, change it after logging in\n\n", adminPassword)
+		}
+	}
+
+	if err = seedServices(dbRepo); err != nil {
+		return err
+	}
+
+	saved, err := dbRepo.AllPreferences()
+	if err != nil {
+		return err
+	}
+
+	missing := preferences.Defaults()
+	for _, p := range saved {
+		delete(missing, p.Name)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.Printf("Setting %d default preferences", len(missing))
+	return dbRepo.InsertOrUpdateSitePreferences(missing, preferences.System)
+}
+
+// seedServices adds the default services that are missing by name. Deleted services count as existing,
+// so a default service an admin has deleted is not added back.
+func seedServices(dbRepo repository.DatabaseRepo) error {
+	names, err := dbRepo.AllServiceNames()
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	for _, s := range defaultServices {
+		if existing[s.ServiceName] {
+			continue
+		}
+
+		if _, err = dbRepo.InsertService(s); err != nil {
+			return err
+		}
+		log.Printf("Created service %s", s.ServiceName)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/go-chi/chi"
 	"net/http"
 	"server_monitor/internal/handlers"
+	"server_monitor/internal/models"
 )
 
 func routes() http.Handler {
@@ -22,6 +23,11 @@ func routes() http.Handler {
 
 	mux.Get("/user/logout", handlers.Repo.Logout)
 
+	// live updates
+	mux.Post("/pusher/auth", handlers.Repo.PusherAuth)
+	mux.Post("/pusher/hook", handlers.Repo.PusherHook)
+	mux.Get("/ws", handlers.Repo.LiveUpdates)
+
 	// admin routes
 	mux.Route("/admin", func(mux chi.Router) {
 		// all admin routes are protected
@@ -33,28 +39,62 @@ func routes() http.Handler {
 		// events
 		mux.Get("/events", handlers.Repo.Events)
 
-		// settings
-		mux.Get("/settings", handlers.Repo.Settings)
-		mux.Post("/settings", handlers.Repo.PostSettings)
-
 		// service status pages (all hosts)
 		mux.Get("/all-healthy", handlers.Repo.AllHealthyServices)
 		mux.Get("/all-warning", handlers.Repo.AllWarningsServices)
 		mux.Get("/all-problems", handlers.Repo.AllProblemServices)
 		mux.Get("/all-pending", handlers.Repo.AllPendingServices)
 
-		// users
-		mux.Get("/users", handlers.Repo.AllUsers)
-		mux.Get("/user/{id}", handlers.Repo.OneUser)
-		mux.Post("/user/{id}", handlers.Repo.PostOneUser)
-		mux.Get("user/delete/{id}", handlers.Repo.Host)
-
 		// schedule
 		mux.Get("/schedule", handlers.Repo.ListEntries)
 
 		// hosts
 		mux.Get("/host/all", handlers.Repo.AllHosts)
 		mux.Get("/host/{id}", handlers.Repo.Host)
+
+		// operators can acknowledge problems and pause checks
+		mux.Group(func(mux chi.Router) {
+			mux.Use(RequireAccess(models.AccessOperator))
+
+			mux.Post("/preference/ajax/toggle-monitoring", handlers.Repo.ToggleMonitoring)
+			mux.Post("/host-service/{id}/acknowledge", handlers.Repo.AcknowledgeHostService)
+			mux.Post("/host-service/{id}/pause", handlers.Repo.PauseHostService)
+		})
+
+		// admins manage users, settings, hosts and mail
+		mux.Group(func(mux chi.Router) {
+			mux.Use(RequireAccess(models.AccessAdmin))
+
+			// settings
+			mux.Get("/settings", handlers.Repo.Settings)
+			mux.Post("/settings", handlers.Repo.PostSettings)
+			mux.Post("/settings/test-email", handlers.Repo.SendTestEmail)
+
+			// users
+			mux.Get("/users", handlers.Repo.AllUsers)
+			mux.Get("/user/{id}", handlers.Repo.OneUser)
+			mux.Post("/user/{id}", handlers.Repo.PostOneUser)
+			mux.Post("/user/delete/{id}", handlers.Repo.DeleteUser)
+			mux.Post("/user/signout/{id}", handlers.Repo.SignOutEverywhere)
+			mux.Post("/user/unlock/{id}", handlers.Repo.UnlockUser)
+
+			// services
+			mux.Get("/services", handlers.Repo.AllServices)
+			mux.Get("/service/{id}", handlers.Repo.OneService)
+			mux.Post("/service/{id}", handlers.Repo.PostService)
+			mux.Post("/service/delete/{id}", handlers.Repo.DeleteService)
+
+			// hosts
+			mux.Post("/host/{id}", handlers.Repo.PostHost)
+			mux.Post("/host/delete/{id}", handlers.Repo.DeleteHost)
+
+			// mail templates
+			mux.Get("/mail-templates", handlers.Repo.MailTemplates)
+
+			// mail queue
+			mux.Get("/mail-queue", handlers.Repo.MailQueue)
+			mux.Post("/mail-queue/retry/{id}", handlers.Repo.RetryMail)
+		})
 	})
 	// static files
 	fileServer := http.FileServer(http.Dir("./static/"))
@@ -6,18 +6,27 @@ import (
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/pusher/pusher-http-go"
+	"github.com/robfig/cron/v3"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"server_monitor/internal/broadcast"
 	"server_monitor/internal/channeldata"
 	"server_monitor/internal/config"
 	"server_monitor/internal/driver"
 	"server_monitor/internal/handlers"
 	"server_monitor/internal/helpers"
+	"server_monitor/internal/mailer"
+	"server_monitor/internal/preferences"
+	"server_monitor/internal/sessionstore"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var (
+	db_type = os.Getenv("DB_TYPE")
 	db_host = os.Getenv("DB_HOST")
 	db_port = os.Getenv("DB_PORT")
 	db_user = os.Getenv("DB_USER")
@@ -26,39 +35,33 @@ var (
 	db_ssl  = os.Getenv("DB_SSL")
 )
 
-func setupDatabase() (*driver.DB, error) {
-	dbHost := flag.String("dbhost", db_host, "database host")
-	dbPort := flag.String("dbport", db_port, "database port")
-	dbUser := flag.String("dbuser", db_user, "database user")
-	dbPass := flag.String("dbpass", db_pass, "database password")
-	databaseName := flag.String("db", db_name, "database name")
-	dbSsl := flag.String("dbssl", db_ssl, "database ssl setting")
-
-	flag.Parse()
-
-	if *dbUser == "" || *dbHost == "" || *dbPort == "" || *databaseName == "" {
+func setupDatabase(cfg driver.Config) (*driver.DB, error) {
+	// sqlite only needs the file name, given with -db
+	missing := cfg.Name == ""
+	if cfg.Type != driver.SQLite {
+		missing = missing || cfg.User == "" || cfg.Host == "" || cfg.Port == ""
+	}
+	if missing {
 		fmt.Println("Missing required flag.")
 		os.Exit(1)
 	}
 
-	log.Println("Connecting to database...")
-	var dsnString string
+	log.Printf("Connecting to %s database...", cfg.Type)
 
-	if *dbPass == "" {
-		dsnString = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
-			*dbHost, *dbPort, *dbUser, *databaseName, *dbSsl)
-	} else {
-		dsnString = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
-			*dbHost, *dbPort, *dbUser, *dbPass, *databaseName, *dbSsl)
-	}
-
-	return driver.ConnectMysql(dsnString)
+	return driver.Connect(cfg)
 }
 
 func setupSessionManger(db *driver.DB, identifier string, inProduction bool) *scs.SessionManager {
 	log.Println("Initializing session manager")
 	session = scs.New()
-	session.Store = mysqlstore.New(db.SQL)
+	switch db.Type {
+	case driver.Postgres:
+		session.Store = sessionstore.NewPostgres(db.SQL, 5*time.Minute)
+	case driver.SQLite:
+		session.Store = sessionstore.NewSqlite(db.SQL, 5*time.Minute)
+	default:
+		session.Store = mysqlstore.New(db.SQL)
+	}
 	session.Lifetime = 24 * time.Hour
 	session.Cookie.Persist = true
 	session.Cookie.Name = fmt.Sprintf("gbsession_id_%s", identifier)
@@ -68,84 +71,187 @@ func setupSessionManger(db *driver.DB, identifier string, inProduction bool) *sc
 	return session
 }
 
-func setupMail() chan channeldata.MailJob {
+func setupMail() *Dispatcher {
 	log.Println("Initializing mail channel and worker pool...")
 	mailQueue := make(chan channeldata.MailJob, maxWorkerPoolSize)
 
+	// mail that was being sent when the app stopped goes out again
+	if err := repo.DB.RequeueSendingMail(); err != nil {
+		log.Println("Cannot requeue unsent mail:", err)
+	}
+
 	// Start the email dispatcher
 	log.Println("Starting email dispatcher...")
-	dispatcher := NewDispatcher(mailQueue, maxJobMaxWorkers)
+	dispatcher = NewDispatcher(mailQueue, maxJobMaxWorkers)
 	dispatcher.run()
 
-	return mailQueue
+	return dispatcher
 }
 
-func setupPreferenceMap(pusherHost, pusherPort, pusherKey, identifier string) map[string]string {
-	log.Println("Getting preferences...")
-	preferenceMap = make(map[string]string)
+func setupTemplateCache(dir string, inProduction bool) *mailer.TemplateCache {
+	if dir == "" {
+		log.Println("Loading embedded mail templates...")
+	} else {
+		log.Println("Loading mail templates from", dir)
+	}
 
-	preferences, err := repo.DB.AllPreferences()
+	// templates read from disk are reloaded on every use in development, so edits show without a restart
+	templateCache, err := mailer.NewTemplateCache(dir, dir != "" && !inProduction)
 	if err != nil {
-		log.Fatal("Cannot read preferences:", err)
+		log.Fatal("Cannot load mail templates:", err)
 	}
 
-	for _, pref := range preferences {
-		preferenceMap[pref.Name] = string(pref.Preference)
+	return templateCache
+}
+
+func setupScheduler() *cron.Cron {
+	log.Println("Starting scheduler...")
+	localZone, _ := time.LoadLocation("Local")
+	scheduler := cron.New(cron.WithLocation(localZone), cron.WithChain(
+		cron.DelayIfStillRunning(cron.DefaultLogger),
+		cron.Recover(cron.DefaultLogger),
+	))
+	scheduler.Start()
+
+	return scheduler
+}
+
+// setupPreferences loads the saved preferences, and adds the settings that come from flags
+func setupPreferences(pusherHost, pusherPort, pusherKey string, pusherSecure bool, identifier string) *preferences.Service {
+	log.Println("Getting preferences...")
+
+	service := preferences.NewService(repo.DB)
+	if err := service.Load(); err != nil {
+		log.Fatal("Cannot read preferences:", err)
 	}
 
-	preferenceMap["pusher-host"] = pusherHost
-	preferenceMap["pusher-port"] = pusherPort
-	preferenceMap["pusher-key"] = pusherKey
-	preferenceMap["identifier"] = identifier
-	preferenceMap["version"] = observerVersion
+	service.SetLocal("pusher-host", pusherHost)
+	service.SetLocal("pusher-port", pusherPort)
+	service.SetLocal("pusher-key", pusherKey)
+	service.SetLocal("pusher-secure", strconv.FormatBool(pusherSecure))
+	service.SetLocal("identifier", identifier)
+	service.SetLocal("version", observerVersion)
 
-	return preferenceMap
+	return service
 }
 
 func setupApp() (string, error) {
-	insecurePort := *flag.String("port", ":4000", "port to listen on")
-	identifier := *flag.String("identifier", "observer", "unique identifier")
-	domain := *flag.String("domain", "localhost", "domain name (e.g. example.com)")
-	inProduction := *flag.Bool("production", false, "application is in production")
-
-	pusherHost := *flag.String("pusherHost", "", "pusher host")
-	pusherPort := *flag.String("pusherPort", "443", "pusher port")
-	pusherApp := *flag.String("pusherApp", "9", "pusher app id")
-	pusherKey := *flag.String("pusherKey", "", "pusher key")
-	pusherSecret := *flag.String("pusherSecret", "", "pusher secret")
-	pusherSecure := *flag.Bool("pusherSecure", false, "pusher server uses SSL (true or false)")
+	portFlag := flag.String("port", ":4000", "port to listen on")
+	identifierFlag := flag.String("identifier", "observer", "unique identifier")
+	domainFlag := flag.String("domain", "localhost", "domain name (e.g. example.com)")
+	inProductionFlag := flag.Bool("production", false, "application is in production")
+	trustedProxiesFlag := flag.String("trustedProxies", "", "comma separated IPs or CIDRs of reverse proxies whose X-Forwarded-For header is trusted")
+	mailTemplatesFlag := flag.String("mailTemplates", "", "directory of mail templates (defaults to the built in templates)")
+
+	pusherHostFlag := flag.String("pusherHost", "", "pusher host")
+	pusherPortFlag := flag.String("pusherPort", "443", "pusher port")
+	pusherAppFlag := flag.String("pusherApp", "9", "pusher app id")
+	pusherKeyFlag := flag.String("pusherKey", "", "pusher key")
+	pusherSecretFlag := flag.String("pusherSecret", "", "pusher secret")
+	pusherSecureFlag := flag.Bool("pusherSecure", false, "pusher server uses SSL (true or false)")
+	broadcasterFlag := flag.String("broadcaster", broadcast.BackendPusher, "live update backend (pusher or websocket)")
+
+	if db_type == "" {
+		db_type = driver.MySQL
+	}
+	dbType := flag.String("dbtype", db_type, "database type (mysql, postgres or sqlite)")
+	dbHost := flag.String("dbhost", db_host, "database host")
+	dbPort := flag.String("dbport", db_port, "database port")
+	dbUser := flag.String("dbuser", db_user, "database user")
+	dbPass := flag.String("dbpass", db_pass, "database password")
+	databaseName := flag.String("db", db_name, "database name, or the database file for sqlite")
+	dbSsl := flag.String("dbssl", db_ssl, "database ssl setting (postgres sslmode, or mysql tls)")
+
+	autoMigrateFlag := flag.Bool("automigrate", true, "apply database migrations and seed data on startup")
+	adminEmailFlag := flag.String("adminEmail", "admin@example.com", "email of the admin user created in an empty database")
+	adminPasswordFlag := flag.String("adminPassword", os.Getenv("ADMIN_PASSWORD"), "password of the admin user created in an empty database (random if not set)")
 
 	flag.Parse()
 
+	// flag values are only set once flag.Parse has run
+	insecurePort := *portFlag
+	identifier := *identifierFlag
+	domain := *domainFlag
+	inProduction := *inProductionFlag
+	mailTemplates := *mailTemplatesFlag
+
+	trustedProxies, err := parseTrustedProxies(*trustedProxiesFlag)
+	if err != nil {
+		log.Fatal("Invalid trustedProxies: ", err)
+	}
+
+	pusherHost := *pusherHostFlag
+	pusherPort := *pusherPortFlag
+	pusherApp := *pusherAppFlag
+	pusherKey := *pusherKeyFlag
+	pusherSecret := *pusherSecretFlag
+	pusherSecure := *pusherSecureFlag
+	broadcaster := *broadcasterFlag
+
 	if identifier == "" {
 		log.Println("Can't configure identifier.")
 		os.Exit(1)
 	}
 
-	db, err := setupDatabase()
+	db, err := setupDatabase(driver.Config{
+		Type:     *dbType,
+		Host:     *dbHost,
+		Port:     *dbPort,
+		User:     *dbUser,
+		Password: *dbPass,
+		Name:     *databaseName,
+		SSL:      *dbSsl,
+	})
 	if err != nil {
 		log.Fatal("Cannot connect to database", err)
 	}
 
+	// app is filled in below; the repository only keeps a pointer to it
+	switch db.Type {
+	case driver.Postgres:
+		repo = handlers.NewPostgresHandlers(db, &app)
+	case driver.SQLite:
+		repo = handlers.NewSqliteHandlers(db, &app)
+	default:
+		repo = handlers.NewMysqlHandlers(db, &app)
+	}
+
+	// app [flags] migrate up|down|status
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("Unknown command %q, expected migrate", args[0])
+		}
+		runMigrateCommand(db, repo.DB, args[1:], *adminEmailFlag, *adminPasswordFlag)
+	}
+
+	if *autoMigrateFlag {
+		if err = migrateDatabase(db, repo.DB, *adminEmailFlag, *adminPasswordFlag); err != nil {
+			log.Fatal("Cannot migrate database: ", err)
+		}
+	}
+
 	session = setupSessionManger(db, identifier, inProduction)
 
-	mailQueue := setupMail()
+	templateCache := setupTemplateCache(mailTemplates, inProduction)
 
 	app = config.AppConfig{
-		DB:           db,
-		Session:      session,
-		InProduction: inProduction,
-		Domain:       domain,
-		PusherSecret: pusherSecret,
-		MailQueue:    mailQueue,
-		Version:      observerVersion,
-		Identifier:   identifier,
+		DB:             db,
+		Session:        session,
+		InProduction:   inProduction,
+		Domain:         domain,
+		PusherSecret:   pusherSecret,
+		TemplateCache:  templateCache,
+		Version:        observerVersion,
+		Identifier:     identifier,
+		TrustedProxies: trustedProxies,
 	}
 
-	repo = handlers.NewMysqlHandlers(db, &app)
 	handlers.NewHandlers(repo, &app)
 
-	app.PreferenceMap = setupPreferenceMap(pusherHost, pusherPort, pusherKey, identifier)
+	// the mail workers read the preferences as soon as they start
+	app.Preferences = setupPreferences(pusherHost, pusherPort, pusherKey, pusherSecure, identifier)
+
+	app.MailQueue = setupMail()
 
 	wsClient = pusher.Client{
 		AppID:  pusherApp,
@@ -160,10 +266,63 @@ func setupApp() (string, error) {
 
 	app.WsClient = wsClient
 
+	switch broadcaster {
+	case broadcast.BackendPusher:
+		if pusherKey != "" {
+			app.Broadcaster = &app.WsClient
+		} else {
+			log.Println("No pusher key, live updates are disabled")
+		}
+	case broadcast.BackendWebSocket:
+		app.Broadcaster = broadcast.NewHub()
+	default:
+		log.Fatalf("Unknown broadcaster %q, expected %s or %s", broadcaster, broadcast.BackendPusher, broadcast.BackendWebSocket)
+	}
+	app.Preferences.SetLocal("broadcaster", broadcaster)
+
 	helpers.NewHelpers(&app)
+
+	app.MonitorMap = config.NewMonitorMap()
+	app.Scheduler = setupScheduler()
+	repo.StartMonitoring()
+	stopWatchingPreferences = repo.WatchPreferences()
+
 	return insecurePort, err
 }
 
+// parseTrustedProxies parses a comma separated list of IP addresses and CIDR ranges
+func parseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+		}
+		proxies = append(proxies, ipNet)
+	}
+
+	return proxies, nil
+}
+
 func createDirIfNotExist(path string) error {
 	const mode = 0755
 
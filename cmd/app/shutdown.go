@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"server_monitor/internal/handlers"
+	"time"
+)
+
+// shutdownTimeout is how long the app waits for requests, checks and mail to finish when it is stopped
+const shutdownTimeout = 30 * time.Second
+
+// shutdown stops the app in order: the HTTP server, the scheduler and the checks it is running,
+// the notifiers sending alerts, then the mail workers once they have sent the queued mail, and finally the database
+func shutdown(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	log.Println("Stopping HTTP server...")
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("HTTP server did not stop cleanly:", err)
+	}
+
+	stopWatchingPreferences()
+
+	log.Println("Stopping scheduler and waiting for running checks...")
+	select {
+	case <-app.Scheduler.Stop().Done():
+	case <-ctx.Done():
+		log.Println("Checks were still running at the shutdown deadline")
+	}
+
+	log.Println("Waiting for notifications to be sent...")
+	if err := handlers.WaitForNotifications(ctx); err != nil {
+		log.Println("Notifications were still being sent at the shutdown deadline:", err)
+	}
+
+	log.Println("Sending queued mail...")
+	if err := dispatcher.stop(ctx); err != nil {
+		log.Println("Mail that was not sent by the shutdown deadline will be sent on the next start:", err)
+	}
+
+	// the session store's cleanup goroutine uses the database
+	if store, ok := session.Store.(interface{ StopCleanup() }); ok {
+		store.StopCleanup()
+	}
+
+	log.Println("Closing database...")
+	if err := app.DB.SQL.Close(); err != nil {
+		log.Println(err)
+	}
+}
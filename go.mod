@@ -2,28 +2,42 @@ module server_monitor
 
 go 1.17
 
+require (
+	github.com/CloudyKit/jet/v6 v6.1.0
+	github.com/alexedwards/scs/mysqlstore v0.0.0-20211203064041-370cc303b69f
+	github.com/alexedwards/scs/v2 v2.5.0
+	github.com/aymerick/douceur v0.2.0
+	github.com/glebarez/go-sqlite v1.22.0
+	github.com/go-chi/chi v1.5.4
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/gorilla/websocket v1.4.2
+	github.com/justinas/nosurf v1.1.1
+	github.com/lib/pq v1.10.9
+	github.com/pusher/pusher-http-go v4.0.1+incompatible
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/xhit/go-simple-mail/v2 v2.10.0
+	golang.org/x/crypto v0.0.0-20220126234351-aa10faf2a1f8
+	jaytaylor.com/html2text v0.0.0-20211105163654-bc68cce691ba
+)
+
 require (
 	github.com/CloudyKit/fastprinter v0.0.0-20200109182630-33d98a066a53 // indirect
-	github.com/CloudyKit/jet/v6 v6.1.0 // indirect
 	github.com/PuerkitoBio/goquery v1.8.0 // indirect
-	github.com/alexedwards/scs/mysqlstore v0.0.0-20211203064041-370cc303b69f // indirect
-	github.com/alexedwards/scs/v2 v2.5.0 // indirect
 	github.com/andybalholm/cascadia v1.3.1 // indirect
-	github.com/aymerick/douceur v0.2.0 // indirect
-	github.com/go-chi/chi v1.5.4 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.5.0 // indirect
 	github.com/gorilla/css v1.0.0 // indirect
 	github.com/gorilla/securecookie v1.1.1 // indirect
 	github.com/gorilla/sessions v1.2.1 // indirect
-	github.com/justinas/nosurf v1.1.1 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
-	github.com/pusher/pusher-http-go v4.0.1+incompatible // indirect
-	github.com/robfig/cron/v3 v3.0.1 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	github.com/ssor/bom v0.0.0-20170718123548-6386211fdfcf // indirect
-	github.com/xhit/go-simple-mail/v2 v2.10.0 // indirect
-	golang.org/x/crypto v0.0.0-20220126234351-aa10faf2a1f8 // indirect
 	golang.org/x/net v0.0.0-20220127200216-cd36cc0744dd // indirect
-	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
-	jaytaylor.com/html2text v0.0.0-20211105163654-bc68cce691ba // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	modernc.org/libc v1.37.6 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.7.2 // indirect
+	modernc.org/sqlite v1.28.0 // indirect
 )
@@ -0,0 +1,13 @@
+package broadcast
+
+// Backends that can deliver live updates to the browser
+const (
+	BackendPusher    = "pusher"
+	BackendWebSocket = "websocket"
+)
+
+// Broadcaster sends an event with data to every client subscribed to a channel.
+// *pusher.Client satisfies it, as does the built in Hub.
+type Broadcaster interface {
+	Trigger(channel string, eventName string, data interface{}) error
+}
@@ -0,0 +1,161 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+
+	// sendBuffer is how many messages may queue for a slow client before it is dropped
+	sendBuffer = 32
+)
+
+// Message is the frame sent to websocket clients; Data is the same payload pusher would deliver
+type Message struct {
+	Channel string      `json:"channel"`
+	Event   string      `json:"event"`
+	Data    interface{} `json:"data"`
+}
+
+// client is one websocket connection subscribed to a channel
+type client struct {
+	channel string
+	conn    *websocket.Conn
+	send    chan []byte
+}
+
+// Hub is a Broadcaster that delivers events to browsers over websockets served by the app itself
+type Hub struct {
+	upgrader websocket.Upgrader
+	mu       sync.RWMutex
+	clients  map[*client]bool
+}
+
+// NewHub creates an empty Hub
+func NewHub() *Hub {
+	return &Hub{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		clients: make(map[*client]bool),
+	}
+}
+
+// Trigger sends an event with data to every client subscribed to channel
+func (h *Hub) Trigger(channel string, eventName string, data interface{}) error {
+	msg, err := json.Marshal(Message{
+		Channel: channel,
+		Event:   eventName,
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		if c.channel != channel {
+			continue
+		}
+
+		select {
+		case c.send <- msg:
+		default:
+			// the client isn't keeping up; closing the connection makes the browser reconnect
+			_ = c.conn.Close()
+		}
+	}
+
+	return nil
+}
+
+// Serve upgrades the request to a websocket subscribed to channel, and blocks until it closes
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, channel string) error {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	c := &client{
+		channel: channel,
+		conn:    conn,
+		send:    make(chan []byte, sendBuffer),
+	}
+
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+
+	go h.writePump(c)
+	h.readPump(c)
+
+	return nil
+}
+
+// readPump discards anything the client sends and unregisters it once the connection closes
+func (h *Hub) readPump(c *client) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+
+		close(c.send)
+		_ = c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(512)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println(err)
+			}
+			return
+		}
+	}
+}
+
+// writePump sends queued messages, and pings, to the client until its send channel is closed
+func (h *Hub) writePump(c *client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -25,5 +25,7 @@ type MailData struct {
 
 // MailJob is the unit of work to be performed when sending an email to chan
 type MailJob struct {
+	// ID is the job's row in the mail queue, or 0 if it hasn't been stored yet
+	ID          int
 	MailMessage MailData
 }
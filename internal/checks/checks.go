@@ -0,0 +1,110 @@
+package checks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status values reported by checks, matching the status stored for a host service
+const (
+	StatusPending = "pending"
+	StatusHealthy = "healthy"
+	StatusWarning = "warning"
+	StatusProblem = "problem"
+)
+
+// IsStatus reports whether s is one of the statuses a host service can be in
+func IsStatus(s string) bool {
+	switch s {
+	case StatusPending, StatusHealthy, StatusWarning, StatusProblem:
+		return true
+	}
+	return false
+}
+
+// Check types, matching the check_type stored for a service
+const (
+	TypeHTTP  = "http"
+	TypeHTTPS = "https"
+	TypeTLS   = "tls"
+	TypeTCP   = "tcp"
+)
+
+// Types lists the check types a service can have
+var Types = []string{TypeHTTP, TypeHTTPS, TypeTLS, TypeTCP}
+
+// IsType reports whether t is a check type
+func IsType(t string) bool {
+	for _, checkType := range Types {
+		if t == checkType {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is the outcome of running a check
+type Result struct {
+	Status   string
+	Message  string
+	Duration time.Duration
+	// CertExpiry and CertIssuer are set by certificate checks
+	CertExpiry time.Time
+	CertIssuer string
+}
+
+// ConfigKeys returns the check_config keys understood by a check type
+func ConfigKeys(checkType string) []string {
+	switch checkType {
+	case TypeHTTP, TypeHTTPS:
+		return httpConfigKeys
+	case TypeTLS:
+		return tlsConfigKeys
+	case TypeTCP:
+		return tcpConfigKeys
+	}
+	return nil
+}
+
+// ValidateConfig returns an error if cfg is not a valid check_config for checkType,
+// so a bad setting is refused when it is saved rather than found when the check runs
+func ValidateConfig(checkType string, cfg map[string]string) error {
+	var err error
+
+	switch checkType {
+	case TypeHTTP, TypeHTTPS:
+		_, err = NewHTTPConfig(checkType+"://example.com", cfg)
+	case TypeTLS:
+		_, err = NewTLSConfig("example.com", cfg)
+	case TypeTCP:
+		_, err = NewTCPConfig("example.com", cfg)
+	}
+
+	return err
+}
+
+// problem returns a problem result with a formatted message
+func problem(start time.Time, format string, a ...interface{}) Result {
+	return Result{
+		Status:   StatusProblem,
+		Message:  fmt.Sprintf(format, a...),
+		Duration: time.Since(start),
+	}
+}
+
+// parseSeconds parses a number of seconds from cfg, returning def if the key is empty
+func parseSeconds(cfg map[string]string, key string, def time.Duration) (time.Duration, error) {
+	v := strings.TrimSpace(cfg[key])
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive number of seconds", key)
+	}
+
+	return time.Duration(n) * time.Second, nil
+}
@@ -0,0 +1,29 @@
+package checks
+
+import "testing"
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkType string
+		cfg       map[string]string
+		wantErr   bool
+	}{
+		{"http defaults", TypeHTTP, nil, false},
+		{"https invalid body regex", TypeHTTPS, map[string]string{"body_match_mode": BodyRegex, "body_match": `(`}, true},
+		{"tls defaults", TypeTLS, nil, false},
+		{"tls problem after warn", TypeTLS, map[string]string{"warn_days": "7", "problem_days": "14"}, true},
+		{"tcp port", TypeTCP, map[string]string{"port": "22"}, false},
+		{"tcp without a port", TypeTCP, nil, true},
+		{"unknown type", "ping", map[string]string{"port": "x"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.checkType, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
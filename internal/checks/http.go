@@ -0,0 +1,215 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Body match modes for an HTTP check
+const (
+	BodyContains    = "contains"
+	BodyNotContains = "not_contains"
+	BodyRegex       = "regex"
+	BodyNotRegex    = "not_regex"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// maxBodySize is the most of a response body that is read when matching
+const maxBodySize = 1 << 20
+
+var httpConfigKeys = []string{"expected_status", "timeout", "body_match_mode", "body_match", "headers", "warn_after_ms"}
+
+// HTTPConfig holds the settings for an HTTP(S) check
+type HTTPConfig struct {
+	URL                 string
+	ExpectedStatusCodes []int
+	Timeout             time.Duration
+	BodyMatchMode       string
+	BodyMatch           string
+	Headers             map[string]string
+	WarnAfter           time.Duration
+	// bodyRegexp is BodyMatch compiled, for the regex match modes
+	bodyRegexp *regexp.Regexp
+}
+
+// NewHTTPConfig builds an HTTPConfig for url from a host service's check_config
+func NewHTTPConfig(url string, cfg map[string]string) (HTTPConfig, error) {
+	c := HTTPConfig{
+		URL:           url,
+		BodyMatchMode: strings.TrimSpace(cfg["body_match_mode"]),
+		BodyMatch:     cfg["body_match"],
+		Headers:       ParseHeaders(cfg["headers"]),
+	}
+
+	for _, code := range strings.Split(cfg["expected_status"], ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 100 || n > 599 {
+			return c, fmt.Errorf("invalid expected status code %q", code)
+		}
+		c.ExpectedStatusCodes = append(c.ExpectedStatusCodes, n)
+	}
+
+	timeout, err := parseSeconds(cfg, "timeout", defaultHTTPTimeout)
+	if err != nil {
+		return c, err
+	}
+	c.Timeout = timeout
+
+	switch c.BodyMatchMode {
+	case "", BodyContains, BodyNotContains:
+	case BodyRegex, BodyNotRegex:
+		re, err := regexp.Compile(c.BodyMatch)
+		if err != nil {
+			return c, fmt.Errorf("invalid body regex: %s", err)
+		}
+		c.bodyRegexp = re
+	default:
+		return c, fmt.Errorf("invalid body match mode %q", c.BodyMatchMode)
+	}
+
+	if v := strings.TrimSpace(cfg["warn_after_ms"]); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 1 {
+			return c, fmt.Errorf("warn_after_ms must be a positive number of milliseconds")
+		}
+		c.WarnAfter = time.Duration(ms) * time.Millisecond
+	}
+
+	return c, nil
+}
+
+// ParseHeaders parses "Name: value" lines into a header map
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+
+	for _, line := range strings.Split(s, "\n") {
+		split := strings.SplitN(line, ":", 2)
+		if len(split) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(split[0])
+		if name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(split[1])
+	}
+
+	return headers
+}
+
+// HTTP requests c.URL and checks the response against the config.
+// Connection errors, unexpected status codes and failed body matches are problems;
+// a healthy response slower than c.WarnAfter is a warning.
+func HTTP(c HTTPConfig) Result {
+	start := time.Now()
+
+	req, err := http.NewRequest(http.MethodGet, c.URL, nil)
+	if err != nil {
+		return problem(start, "invalid request: %s", err)
+	}
+
+	for name, value := range c.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if c.expectsRedirect() {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return problem(start, "%s - %s", c.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if !c.expectsStatus(resp.StatusCode) {
+		return problem(start, "%s - unexpected status %s", c.URL, resp.Status)
+	}
+
+	if c.BodyMatchMode != "" {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		if err != nil {
+			return problem(start, "%s - could not read body: %s", c.URL, err)
+		}
+
+		var found bool
+		if c.bodyRegexp != nil {
+			found = c.bodyRegexp.Match(body)
+		} else {
+			found = strings.Contains(string(body), c.BodyMatch)
+		}
+
+		switch c.BodyMatchMode {
+		case BodyContains, BodyRegex:
+			if !found {
+				return problem(start, "%s - body does not match %q", c.URL, c.BodyMatch)
+			}
+		case BodyNotContains, BodyNotRegex:
+			if found {
+				return problem(start, "%s - body matches %q", c.URL, c.BodyMatch)
+			}
+		}
+	}
+
+	duration := time.Since(start)
+
+	if c.WarnAfter > 0 && duration > c.WarnAfter {
+		return Result{
+			Status:   StatusWarning,
+			Message:  fmt.Sprintf("%s - %s in %s, slower than %s", c.URL, resp.Status, duration.Round(time.Millisecond), c.WarnAfter),
+			Duration: duration,
+		}
+	}
+
+	return Result{
+		Status:   StatusHealthy,
+		Message:  fmt.Sprintf("%s - %s", c.URL, resp.Status),
+		Duration: duration,
+	}
+}
+
+// expectsStatus reports whether code is an expected status; any 2xx is expected if none are set
+func (c HTTPConfig) expectsStatus(code int) bool {
+	if len(c.ExpectedStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	for _, expected := range c.ExpectedStatusCodes {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// expectsRedirect reports whether a 3xx status is expected, in which case redirects are not followed
+func (c HTTPConfig) expectsRedirect() bool {
+	for _, expected := range c.ExpectedStatusCodes {
+		if expected >= 300 && expected < 400 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package checks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTPTestServer serves a few fixed paths for the HTTP check tests
+func newHTTPTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "status: all systems operational")
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database unavailable", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved-to-error", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/error", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(1500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		fmt.Fprint(w, "finally")
+	})
+	mux.HandleFunc("/sluggish", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Host != "status.example.com" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "welcome")
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestHTTP(t *testing.T) {
+	srv := newHTTPTestServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		cfg        map[string]string
+		wantStatus string
+		wantInMsg  string
+	}{
+		{"any 2xx by default", "/ok", nil, StatusHealthy, "200 OK"},
+		{"5xx is a problem", "/error", nil, StatusProblem, "unexpected status 500"},
+		{"4xx is a problem", "/missing", nil, StatusProblem, "unexpected status 404"},
+		{"expected 404", "/missing", map[string]string{"expected_status": "404"}, StatusHealthy, "404"},
+		{"one of several expected", "/missing", map[string]string{"expected_status": "200, 404"}, StatusHealthy, "404"},
+		{"200 not in expected list", "/ok", map[string]string{"expected_status": "204"}, StatusProblem, "unexpected status 200"},
+
+		{"redirect is followed", "/moved", nil, StatusHealthy, "200 OK"},
+		{"redirect to an error", "/moved-to-error", nil, StatusProblem, "unexpected status 500"},
+		{"expected redirect isn't followed", "/moved", map[string]string{"expected_status": "301"}, StatusHealthy, "301"},
+		{"different redirect expected", "/moved-to-error", map[string]string{"expected_status": "301"}, StatusProblem, "unexpected status 302"},
+
+		{"timeout", "/slow", map[string]string{"timeout": "1"}, StatusProblem, "Timeout"},
+		{"slower than warn_after_ms", "/sluggish", map[string]string{"warn_after_ms": "1"}, StatusWarning, "slower than 1ms"},
+		{"faster than warn_after_ms", "/ok", map[string]string{"warn_after_ms": "5000"}, StatusHealthy, "200 OK"},
+
+		{"contains", "/ok", map[string]string{"body_match_mode": BodyContains, "body_match": "operational"}, StatusHealthy, "200 OK"},
+		{"does not contain", "/ok", map[string]string{"body_match_mode": BodyContains, "body_match": "degraded"}, StatusProblem, `body does not match "degraded"`},
+		{"not_contains", "/ok", map[string]string{"body_match_mode": BodyNotContains, "body_match": "degraded"}, StatusHealthy, "200 OK"},
+		{"not_contains found", "/ok", map[string]string{"body_match_mode": BodyNotContains, "body_match": "operational"}, StatusProblem, `body matches "operational"`},
+		{"regex", "/ok", map[string]string{"body_match_mode": BodyRegex, "body_match": `^status: \w+`}, StatusHealthy, "200 OK"},
+		{"regex no match", "/ok", map[string]string{"body_match_mode": BodyRegex, "body_match": `^\d+$`}, StatusProblem, "body does not match"},
+		{"not_regex", "/ok", map[string]string{"body_match_mode": BodyNotRegex, "body_match": `(?i)error`}, StatusHealthy, "200 OK"},
+		{"not_regex match", "/ok", map[string]string{"body_match_mode": BodyNotRegex, "body_match": `systems?`}, StatusProblem, "body matches"},
+		{"body isn't read for an unexpected status", "/error", map[string]string{"body_match_mode": BodyContains, "body_match": "unavailable"}, StatusProblem, "unexpected status 500"},
+
+		{"headers and host are sent", "/headers", map[string]string{"headers": "Authorization: Bearer secret\nHost: status.example.com"}, StatusHealthy, "200 OK"},
+		{"without headers", "/headers", nil, StatusProblem, "unexpected status 403"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewHTTPConfig(srv.URL+tt.path, tt.cfg)
+			if err != nil {
+				t.Fatalf("NewHTTPConfig: %s", err)
+			}
+
+			result := HTTP(c)
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (message %q)", result.Status, tt.wantStatus, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantInMsg) {
+				t.Errorf("message = %q, want it to contain %q", result.Message, tt.wantInMsg)
+			}
+			if result.Duration <= 0 {
+				t.Errorf("duration = %s, want it measured", result.Duration)
+			}
+		})
+	}
+}
+
+func TestHTTPConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c, err := NewHTTPConfig(url, nil)
+	if err != nil {
+		t.Fatalf("NewHTTPConfig: %s", err)
+	}
+
+	if result := HTTP(c); result.Status != StatusProblem {
+		t.Errorf("status = %q, want %q (message %q)", result.Status, StatusProblem, result.Message)
+	}
+}
+
+func TestNewHTTPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]string
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"status list", map[string]string{"expected_status": "200, 301,"}, false},
+		{"status not a number", map[string]string{"expected_status": "ok"}, true},
+		{"status out of range", map[string]string{"expected_status": "600"}, true},
+		{"timeout", map[string]string{"timeout": "5"}, false},
+		{"zero timeout", map[string]string{"timeout": "0"}, true},
+		{"timeout with unit", map[string]string{"timeout": "5s"}, true},
+		{"unknown body match mode", map[string]string{"body_match_mode": "equals"}, true},
+		{"regex", map[string]string{"body_match_mode": BodyRegex, "body_match": `^status: \w+`}, false},
+		{"invalid regex", map[string]string{"body_match_mode": BodyRegex, "body_match": `(`}, true},
+		{"invalid not_regex", map[string]string{"body_match_mode": BodyNotRegex, "body_match": `[a-`}, true},
+		{"contains isn't a regex", map[string]string{"body_match_mode": BodyContains, "body_match": `(`}, false},
+		{"warn_after_ms", map[string]string{"warn_after_ms": "250"}, false},
+		{"negative warn_after_ms", map[string]string{"warn_after_ms": "-1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPConfig("http://example.com", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers := ParseHeaders("Accept: text/html\n\nnot a header\n: no name\nX-Token:  a:b \r")
+
+	want := map[string]string{"Accept": "text/html", "X-Token": "a:b"}
+	if len(headers) != len(want) {
+		t.Fatalf("headers = %v, want %v", headers, want)
+	}
+	for name, value := range want {
+		if headers[name] != value {
+			t.Errorf("%s = %q, want %q", name, headers[name], value)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package checks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Banner match modes for a TCP check
+const (
+	BannerPrefix = "prefix"
+	BannerRegex  = "regex"
+)
+
+const defaultTCPTimeout = 5 * time.Second
+
+// maxBannerSize is the most of a response that is read when matching a banner
+const maxBannerSize = 4096
+
+var tcpConfigKeys = []string{"port", "timeout", "send", "expect_mode", "expect"}
+
+// escapes are the escape sequences allowed in a TCP check payload
+var escapes = strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+// TCPConfig holds the settings for a TCP port and banner check
+type TCPConfig struct {
+	Address    string
+	Timeout    time.Duration
+	Send       string
+	ExpectMode string
+	Expect     string
+	// bannerRegexp is Expect compiled, for the regex expect mode
+	bannerRegexp *regexp.Regexp
+}
+
+// NewTCPConfig builds a TCPConfig for host from a host service's check_config
+func NewTCPConfig(host string, cfg map[string]string) (TCPConfig, error) {
+	c := TCPConfig{
+		Send:       escapes.Replace(cfg["send"]),
+		ExpectMode: strings.TrimSpace(cfg["expect_mode"]),
+		Expect:     cfg["expect"],
+	}
+
+	port := strings.TrimSpace(cfg["port"])
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return c, fmt.Errorf("invalid port %q", port)
+	}
+	c.Address = net.JoinHostPort(host, port)
+
+	timeout, err := parseSeconds(cfg, "timeout", defaultTCPTimeout)
+	if err != nil {
+		return c, err
+	}
+	c.Timeout = timeout
+
+	switch c.ExpectMode {
+	case "":
+		if c.Expect != "" {
+			c.ExpectMode = BannerPrefix
+		}
+	case BannerPrefix:
+	case BannerRegex:
+		re, err := regexp.Compile(c.Expect)
+		if err != nil {
+			return c, fmt.Errorf("invalid banner regex: %s", err)
+		}
+		c.bannerRegexp = re
+	default:
+		return c, fmt.Errorf("invalid expect mode %q", c.ExpectMode)
+	}
+
+	return c, nil
+}
+
+// TCP connects to c.Address, optionally sends c.Send and matches the response against c.Expect.
+// Connection errors, timeouts and banners that do not match are problems.
+func TCP(c TCPConfig) Result {
+	start := time.Now()
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultTCPTimeout
+	}
+
+	conn, err := net.DialTimeout("tcp", c.Address, timeout)
+	if err != nil {
+		return problem(start, "%s - %s", c.Address, err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(start.Add(timeout))
+
+	if c.Send != "" {
+		if _, err = io.WriteString(conn, c.Send); err != nil {
+			return problem(start, "%s - could not send payload: %s", c.Address, err)
+		}
+	}
+
+	if c.ExpectMode == "" {
+		return Result{
+			Status:   StatusHealthy,
+			Message:  fmt.Sprintf("%s - connected", c.Address),
+			Duration: time.Since(start),
+		}
+	}
+
+	banner, err := readBanner(conn)
+	if err != nil {
+		return problem(start, "%s - could not read banner: %s", c.Address, err)
+	}
+
+	var matched bool
+	if c.bannerRegexp != nil {
+		matched = c.bannerRegexp.MatchString(banner)
+	} else {
+		matched = strings.HasPrefix(banner, c.Expect)
+	}
+
+	if !matched {
+		return problem(start, "%s - unexpected banner %q", c.Address, banner)
+	}
+
+	return Result{
+		Status:   StatusHealthy,
+		Message:  fmt.Sprintf("%s - %s", c.Address, banner),
+		Duration: time.Since(start),
+	}
+}
+
+// readBanner reads the first line the server sends, or as much as it sends before closing
+func readBanner(conn net.Conn) (string, error) {
+	reader := bufio.NewReader(io.LimitReader(conn, maxBannerSize))
+
+	line, err := reader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,136 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// newTCPTestServer accepts connections on a loopback port and hands each to serve, returning the port
+func newTCPTestServer(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	return fmt.Sprint(ln.Addr().(*net.TCPAddr).Port)
+}
+
+// sendBanner greets each client as an SSH server does
+func sendBanner(conn net.Conn) {
+	fmt.Fprint(conn, "SSH-2.0-OpenSSH_8.9\r\n")
+}
+
+// echoLine answers the first line a client sends with +OK and the line
+func echoLine(conn net.Conn) {
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(conn, "+OK %s", line)
+}
+
+func TestTCP(t *testing.T) {
+	bannerPort := newTCPTestServer(t, sendBanner)
+	echoPort := newTCPTestServer(t, echoLine)
+
+	tests := []struct {
+		name       string
+		cfg        map[string]string
+		wantStatus string
+		wantInMsg  string
+	}{
+		{"open", map[string]string{"port": bannerPort}, StatusHealthy, "connected"},
+		{"banner prefix", map[string]string{"port": bannerPort, "expect": "SSH-2.0-"}, StatusHealthy, "SSH-2.0-OpenSSH_8.9"},
+		{"banner prefix mismatch", map[string]string{"port": bannerPort, "expect": "220 "}, StatusProblem, `unexpected banner "SSH-2.0-OpenSSH_8.9"`},
+		{"banner regex", map[string]string{"port": bannerPort, "expect_mode": BannerRegex, "expect": `^SSH-2\.0-OpenSSH_\d+`}, StatusHealthy, "SSH-2.0-OpenSSH_8.9"},
+		{"banner regex mismatch", map[string]string{"port": bannerPort, "expect_mode": BannerRegex, "expect": `^SSH-1\.`}, StatusProblem, "unexpected banner"},
+		{"payload is sent", map[string]string{"port": echoPort, "send": `PING\r\n`, "expect": "+OK PING"}, StatusHealthy, "+OK PING"},
+		{"no reply", map[string]string{"port": echoPort, "timeout": "1", "expect": "+OK"}, StatusProblem, "could not read banner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewTCPConfig("127.0.0.1", tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTCPConfig: %s", err)
+			}
+
+			result := TCP(c)
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (message %q)", result.Status, tt.wantStatus, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantInMsg) {
+				t.Errorf("message = %q, want it to contain %q", result.Message, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestTCPClosedPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := fmt.Sprint(ln.Addr().(*net.TCPAddr).Port)
+	_ = ln.Close()
+
+	c, err := NewTCPConfig("127.0.0.1", map[string]string{"port": port})
+	if err != nil {
+		t.Fatalf("NewTCPConfig: %s", err)
+	}
+
+	if result := TCP(c); result.Status != StatusProblem {
+		t.Errorf("status = %q, want problem (message %q)", result.Status, result.Message)
+	}
+}
+
+func TestNewTCPConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      map[string]string
+		wantMode string
+		wantErr  bool
+	}{
+		{"port only", map[string]string{"port": "22"}, "", false},
+		{"no port", nil, "", true},
+		{"port out of range", map[string]string{"port": "65536"}, "", true},
+		{"zero timeout", map[string]string{"port": "22", "timeout": "0"}, "", true},
+		{"expect defaults to prefix", map[string]string{"port": "22", "expect": "SSH-"}, BannerPrefix, false},
+		{"regex", map[string]string{"port": "22", "expect_mode": BannerRegex, "expect": `^SSH-\d`}, BannerRegex, false},
+		{"invalid regex", map[string]string{"port": "22", "expect_mode": BannerRegex, "expect": `SSH-(`}, "", true},
+		{"prefix isn't a regex", map[string]string{"port": "22", "expect_mode": BannerPrefix, "expect": `SSH-(`}, BannerPrefix, false},
+		{"unknown expect mode", map[string]string{"port": "22", "expect_mode": "exact"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewTCPConfig("127.0.0.1", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && c.ExpectMode != tt.wantMode {
+				t.Errorf("expect mode = %q, want %q", c.ExpectMode, tt.wantMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package checks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTLSPort        = "443"
+	defaultTLSTimeout     = 10 * time.Second
+	defaultTLSWarnDays    = 30
+	defaultTLSProblemDays = 7
+)
+
+var tlsConfigKeys = []string{"port", "timeout", "warn_days", "problem_days"}
+
+// TLSConfig holds the settings for a certificate expiry check
+type TLSConfig struct {
+	Address     string
+	ServerName  string
+	Timeout     time.Duration
+	WarnDays    int
+	ProblemDays int
+	// RootCAs is the pool used to verify the chain; nil uses the system pool
+	RootCAs *x509.CertPool
+}
+
+// NewTLSConfig builds a TLSConfig for host from a host service's check_config
+func NewTLSConfig(host string, cfg map[string]string) (TLSConfig, error) {
+	c := TLSConfig{
+		ServerName:  host,
+		WarnDays:    defaultTLSWarnDays,
+		ProblemDays: defaultTLSProblemDays,
+	}
+
+	port := strings.TrimSpace(cfg["port"])
+	if port == "" {
+		port = defaultTLSPort
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return c, fmt.Errorf("invalid port %q", port)
+	}
+	c.Address = net.JoinHostPort(host, port)
+
+	timeout, err := parseSeconds(cfg, "timeout", defaultTLSTimeout)
+	if err != nil {
+		return c, err
+	}
+	c.Timeout = timeout
+
+	if c.WarnDays, err = parseDays(cfg, "warn_days", defaultTLSWarnDays); err != nil {
+		return c, err
+	}
+	if c.ProblemDays, err = parseDays(cfg, "problem_days", defaultTLSProblemDays); err != nil {
+		return c, err
+	}
+	if c.ProblemDays > c.WarnDays {
+		return c, errors.New("problem_days must not be greater than warn_days")
+	}
+
+	return c, nil
+}
+
+// TLS connects to c.Address and checks the peer certificate chain.
+// Connection errors, hostname mismatches, untrusted chains and certificates expiring
+// within c.ProblemDays are problems; expiring within c.WarnDays is a warning.
+func TLS(c TLSConfig) Result {
+	start := time.Now()
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultTLSTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", c.Address, &tls.Config{
+		ServerName: c.ServerName,
+		RootCAs:    c.RootCAs,
+	})
+	if err != nil {
+		result := problem(start, "%s - %s", c.Address, describeTLSError(err))
+
+		// read the certificate without verifying it, so the host page can still show its expiry and issuer
+		if leaf, err := peerCertificate(dialer, c); err == nil {
+			result.CertExpiry = leaf.NotAfter
+			result.CertIssuer = certIssuer(leaf)
+		}
+
+		return result
+	}
+	defer conn.Close()
+
+	leaf := conn.ConnectionState().PeerCertificates[0]
+	duration := time.Since(start)
+	daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
+
+	result := Result{
+		Status:     StatusHealthy,
+		Message:    fmt.Sprintf("%s - certificate expires in %d days (%s)", c.Address, daysLeft, leaf.NotAfter.Format("2006-01-02")),
+		Duration:   duration,
+		CertExpiry: leaf.NotAfter,
+		CertIssuer: certIssuer(leaf),
+	}
+
+	switch {
+	case daysLeft <= c.ProblemDays:
+		result.Status = StatusProblem
+	case daysLeft <= c.WarnDays:
+		result.Status = StatusWarning
+	}
+
+	return result
+}
+
+// peerCertificate returns the leaf certificate presented at c.Address, without verifying it
+func peerCertificate(dialer *net.Dialer, c TLSConfig) (*x509.Certificate, error) {
+	conn, err := tls.DialWithDialer(dialer, "tcp", c.Address, &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+// describeTLSError returns a readable reason for a failed handshake
+func describeTLSError(err error) string {
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("hostname mismatch: %s", hostnameErr.Error())
+	case errors.As(err, &authorityErr):
+		return "untrusted certificate chain: certificate signed by unknown authority"
+	case errors.As(err, &invalidErr):
+		return fmt.Sprintf("invalid certificate: %s", invalidErr.Error())
+	}
+
+	return err.Error()
+}
+
+// certIssuer returns the issuer's common name, or the full issuer name if it has none
+func certIssuer(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	return cert.Issuer.String()
+}
+
+// parseDays parses a non-negative number of days from cfg, returning def if the key is empty
+func parseDays(cfg map[string]string, key string, def int) (int, error) {
+	v := strings.TrimSpace(cfg[key])
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a number of days", key)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,216 @@
+package checks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA signs the leaf certificates served by the TLS check tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Observer Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// leaf returns a certificate for localhost and 127.0.0.1, valid from notBefore to notAfter
+func (ca *testCA) leaf(t *testing.T, notBefore, notAfter time.Time) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serveTLS listens on a loopback port with cert until the test ends, and returns the address
+func serveTLS(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	now := time.Now()
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		notBefore  time.Time
+		notAfter   time.Time
+		serverName string
+		untrusted  bool
+		wantStatus string
+		wantInMsg  string
+	}{
+		{"valid", now.Add(-day), now.Add(90 * day), "localhost", false, StatusHealthy, "certificate expires in"},
+		{"within warn_days", now.Add(-day), now.Add(20 * day), "localhost", false, StatusWarning, "certificate expires in"},
+		{"within problem_days", now.Add(-day), now.Add(3 * day), "localhost", false, StatusProblem, "certificate expires in"},
+		{"expired", now.Add(-90 * day), now.Add(-day), "localhost", false, StatusProblem, "invalid certificate"},
+		{"not yet valid", now.Add(day), now.Add(90 * day), "localhost", false, StatusProblem, "invalid certificate"},
+		{"hostname mismatch", now.Add(-day), now.Add(90 * day), "monitor.example.com", false, StatusProblem, "hostname mismatch"},
+		{"untrusted chain", now.Add(-day), now.Add(90 * day), "localhost", true, StatusProblem, "untrusted certificate chain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := serveTLS(t, ca.leaf(t, tt.notBefore, tt.notAfter))
+
+			c := TLSConfig{
+				Address:     address,
+				ServerName:  tt.serverName,
+				Timeout:     5 * time.Second,
+				WarnDays:    30,
+				ProblemDays: 7,
+				RootCAs:     ca.pool,
+			}
+			if tt.untrusted {
+				c.RootCAs = x509.NewCertPool()
+			}
+
+			result := TLS(c)
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (message %q)", result.Status, tt.wantStatus, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantInMsg) {
+				t.Errorf("message = %q, want it to contain %q", result.Message, tt.wantInMsg)
+			}
+
+			// failed checks read the certificate again without verifying it, so the expiry and
+			// issuer are known whether or not the chain checks out
+			if !result.CertExpiry.Equal(tt.notAfter.Truncate(time.Second)) {
+				t.Errorf("cert expiry = %s, want %s", result.CertExpiry, tt.notAfter.Truncate(time.Second))
+			}
+			if result.CertIssuer != "Observer Test CA" {
+				t.Errorf("cert issuer = %q, want %q", result.CertIssuer, "Observer Test CA")
+			}
+		})
+	}
+}
+
+func TestTLSNotListening(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	result := TLS(TLSConfig{Address: address, ServerName: "localhost", Timeout: time.Second})
+
+	if result.Status != StatusProblem {
+		t.Errorf("status = %q, want %q (message %q)", result.Status, StatusProblem, result.Message)
+	}
+	if !result.CertExpiry.IsZero() || result.CertIssuer != "" {
+		t.Errorf("cert expiry %s and issuer %q, want none without a certificate", result.CertExpiry, result.CertIssuer)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         map[string]string
+		wantAddress string
+		wantErr     bool
+	}{
+		{"defaults", nil, "example.com:443", false},
+		{"port", map[string]string{"port": "8443"}, "example.com:8443", false},
+		{"bad port", map[string]string{"port": "70000"}, "", true},
+		{"thresholds", map[string]string{"warn_days": "14", "problem_days": "14"}, "example.com:443", false},
+		{"problem after warn", map[string]string{"warn_days": "5", "problem_days": "10"}, "", true},
+		{"negative days", map[string]string{"warn_days": "-1"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewTLSConfig("example.com", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.Address != tt.wantAddress {
+				t.Errorf("address = %q, want %q", c.Address, tt.wantAddress)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"github.com/alexedwards/scs/v2"
 	"github.com/pusher/pusher-http-go"
 	"github.com/robfig/cron/v3"
-	"html/template"
+	"net"
+	"server_monitor/internal/broadcast"
 	"server_monitor/internal/channeldata"
 	"server_monitor/internal/driver"
+	"server_monitor/internal/mailer"
+	"server_monitor/internal/preferences"
 )
 
 type AppConfig struct {
@@ -14,13 +17,22 @@ type AppConfig struct {
 	Session       *scs.SessionManager
 	InProduction  bool
 	Domain        string
-	MonitorMap    map[int]cron.EntryID
-	PreferenceMap map[string]string
+	MonitorMap    *MonitorMap
+	Preferences   *preferences.Service
 	Scheduler     *cron.Cron
 	WsClient      pusher.Client
+	Broadcaster   broadcast.Broadcaster
 	PusherSecret  string
-	TemplateCache map[string]*template.Template
-	MailQueue     chan channeldata.MailJob
+	TemplateCache *mailer.TemplateCache
+	MailQueue     MailQueue
 	Version       string
 	Identifier    string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header gives the client's address
+	TrustedProxies []*net.IPNet
+}
+
+// MailQueue takes mail to be sent by the mail workers
+type MailQueue interface {
+	// Add stores mail in the mail queue and hands it to a worker
+	Add(mailMessage channeldata.MailData)
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"github.com/robfig/cron/v3"
+	"sync"
+)
+
+// MonitorMap records the scheduler entry for each monitored host service, by host service id.
+// It is safe for concurrent use.
+type MonitorMap struct {
+	mu      sync.RWMutex
+	entries map[int]cron.EntryID
+}
+
+// NewMonitorMap returns an empty monitor map
+func NewMonitorMap() *MonitorMap {
+	return &MonitorMap{
+		entries: make(map[int]cron.EntryID),
+	}
+}
+
+// Get returns the scheduler entry for a host service
+func (m *MonitorMap) Get(hostServiceID int) (cron.EntryID, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entryID, ok := m.entries[hostServiceID]
+	return entryID, ok
+}
+
+// Update changes the scheduler entry for a host service with the map locked, so the scheduler and the map
+// change together. f is given the current entry, if any, and returns the entry to record, or false to record
+// none. f must not use the map.
+func (m *MonitorMap) Update(hostServiceID int, f func(entryID cron.EntryID, ok bool) (cron.EntryID, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.entries[hostServiceID]
+	entryID, keep := f(old, ok)
+	if keep {
+		m.entries[hostServiceID] = entryID
+	} else {
+		delete(m.entries, hostServiceID)
+	}
+}
+
+// Clear removes every entry, calling f for each with the map locked. f must not use the map.
+func (m *MonitorMap) Clear(f func(hostServiceID int, entryID cron.EntryID)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hostServiceID, entryID := range m.entries {
+		f(hostServiceID, entryID)
+		delete(m.entries, hostServiceID)
+	}
+}
+
+// Snapshot returns a copy of every entry, by host service id. Later changes don't affect it.
+func (m *MonitorMap) Snapshot() map[int]cron.EntryID {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[int]cron.EntryID, len(m.entries))
+	for id, entryID := range m.entries {
+		snapshot[id] = entryID
+	}
+
+	return snapshot
+}
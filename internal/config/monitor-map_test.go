@@ -0,0 +1,155 @@
+package config
+
+import (
+	"github.com/robfig/cron/v3"
+	"sync"
+	"testing"
+)
+
+// fakeScheduler stands in for the cron scheduler, recording which entries are registered
+type fakeScheduler struct {
+	mu      sync.Mutex
+	next    cron.EntryID
+	entries map[cron.EntryID]bool
+	removed map[cron.EntryID]int
+}
+
+func newFakeScheduler() *fakeScheduler {
+	return &fakeScheduler{entries: make(map[cron.EntryID]bool), removed: make(map[cron.EntryID]int)}
+}
+
+func (s *fakeScheduler) add() cron.EntryID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	s.entries[s.next] = true
+	return s.next
+}
+
+func (s *fakeScheduler) remove(entryID cron.EntryID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, entryID)
+	s.removed[entryID]++
+}
+
+// replace is an Update function that swaps the host service's entry for a new one
+func (s *fakeScheduler) replace(old cron.EntryID, ok bool) (cron.EntryID, bool) {
+	if ok {
+		s.remove(old)
+	}
+	return s.add(), true
+}
+
+func TestMonitorMap(t *testing.T) {
+	m := NewMonitorMap()
+
+	if _, ok := m.Get(1); ok {
+		t.Error("empty map has an entry")
+	}
+
+	m.Update(1, func(old cron.EntryID, ok bool) (cron.EntryID, bool) {
+		if ok {
+			t.Errorf("first update was given entry %d", old)
+		}
+		return 10, true
+	})
+	m.Update(1, func(old cron.EntryID, ok bool) (cron.EntryID, bool) {
+		if !ok || old != 10 {
+			t.Errorf("update was given %d, %t, want 10, true", old, ok)
+		}
+		return 11, true
+	})
+	if entryID, ok := m.Get(1); !ok || entryID != 11 {
+		t.Errorf("get returned %d, %t, want 11, true", entryID, ok)
+	}
+
+	snapshot := m.Snapshot()
+	m.Update(2, func(cron.EntryID, bool) (cron.EntryID, bool) { return 20, true })
+	if len(snapshot) != 1 || snapshot[1] != 11 {
+		t.Errorf("snapshot = %v, want only 1: 11, unchanged by later updates", snapshot)
+	}
+
+	m.Update(2, func(cron.EntryID, bool) (cron.EntryID, bool) { return 0, false })
+	if _, ok := m.Get(2); ok {
+		t.Error("entry kept after an update that returned false")
+	}
+
+	cleared := make(map[int]cron.EntryID)
+	m.Clear(func(hostServiceID int, entryID cron.EntryID) {
+		cleared[hostServiceID] = entryID
+	})
+	if len(cleared) != 1 || cleared[1] != 11 {
+		t.Errorf("cleared %v, want only 1: 11", cleared)
+	}
+	if snapshot := m.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("map has %d entries after clear", len(snapshot))
+	}
+}
+
+// TestMonitorMapConcurrent uses the map from several goroutines, for go test -race. Host services are
+// re-registered from two goroutines while the map is read and cleared, as saving a host service races
+// switching monitoring off. Once the map is cleared a last time no scheduler entry may be left running,
+// and every entry must have been removed exactly once.
+func TestMonitorMapConcurrent(t *testing.T) {
+	m := NewMonitorMap()
+	scheduler := newFakeScheduler()
+
+	const hostServices, writers, updates = 20, 2, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				for id := 1; id <= hostServices; id++ {
+					m.Update(id, scheduler.replace)
+				}
+			}
+		}()
+	}
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				for id := 1; id <= hostServices; id++ {
+					m.Get(id)
+				}
+				m.Snapshot()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			m.Clear(func(_ int, entryID cron.EntryID) {
+				scheduler.remove(entryID)
+			})
+		}
+	}()
+
+	wg.Wait()
+
+	m.Clear(func(_ int, entryID cron.EntryID) {
+		scheduler.remove(entryID)
+	})
+
+	if len(scheduler.entries) != 0 {
+		t.Errorf("%d scheduler entries left with no map entry", len(scheduler.entries))
+	}
+	if want := hostServices * writers * updates; len(scheduler.removed) != want {
+		t.Errorf("%d entries removed, want %d", len(scheduler.removed), want)
+	}
+	for entryID, n := range scheduler.removed {
+		if n != 1 {
+			t.Errorf("entry %d removed %d times", entryID, n)
+		}
+	}
+}
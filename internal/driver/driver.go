@@ -2,14 +2,41 @@ package driver
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	"log"
+	"net"
+	"net/url"
 	"time"
 )
 
+// Supported database types
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
 type DB struct {
 	SQL *sql.DB
+	// Type is the kind of database, MySQL, Postgres or SQLite
+	Type string
+}
+
+// Config holds how to reach the database
+type Config struct {
+	Type     string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	// Name is the database name, or the file path for SQLite
+	Name string
+	// SSL is the postgres sslmode, or the mysql driver tls setting
+	SSL string
 }
 
 var dbConn = &DB{}
@@ -17,22 +44,139 @@ var dbConn = &DB{}
 const maxOpenDbConn = 25
 const maxIdleDbConn = 25
 const maxDbLifetime = 5 * time.Minute
+const connectTimeout = 5 * time.Second
+
+// Connect opens and pings the database described by cfg
+func Connect(cfg Config) (*DB, error) {
+	switch cfg.Type {
+	case MySQL:
+		return ConnectMysql(MysqlDSN(cfg))
+	case Postgres:
+		return ConnectPostgres(PostgresDSN(cfg))
+	case SQLite:
+		return ConnectSqlite(cfg.Name)
+	}
+
+	return nil, fmt.Errorf("unknown database type %q, expected %s, %s or %s", cfg.Type, MySQL, Postgres, SQLite)
+}
 
 func ConnectMysql(dsn string) (*DB, error) {
-	db, err := sql.Open("mysql", dsn)
+	return connect(MySQL, "mysql", dsn)
+}
+
+// ConnectPostgres opens a postgres database
+func ConnectPostgres(dsn string) (*DB, error) {
+	return connect(Postgres, "postgres", dsn)
+}
+
+// ConnectSqlite opens the SQLite database in the file at path, creating the file if it doesn't exist
+func ConnectSqlite(path string) (*DB, error) {
+	return connect(SQLite, "sqlite", SqliteDSN(path))
+}
+
+// connect opens a database with the sql driver driverName, and pings it
+func connect(dbType, driverName, dsn string) (*DB, error) {
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	db.SetMaxOpenConns(maxOpenDbConn)
 	db.SetMaxIdleConns(maxIdleDbConn)
 	db.SetConnMaxLifetime(maxDbLifetime)
 	dbConn.SQL = db
+	dbConn.Type = dbType
 
 	err = testDB(err, db)
 
 	return dbConn, err
 }
 
+// MysqlDSN returns the go-sql-driver/mysql data source name for cfg. Times are read as UTC time.Time values.
+func MysqlDSN(cfg Config) string {
+	c := mysql.NewConfig()
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	c.User = cfg.User
+	c.Passwd = cfg.Password
+	c.DBName = cfg.Name
+	c.ParseTime = true
+	c.Loc = time.UTC
+	c.Timeout = connectTimeout
+
+	c.TLSConfig = cfg.SSL
+	if c.TLSConfig == "" {
+		c.TLSConfig = "false"
+	}
+
+	return c.FormatDSN()
+}
+
+// PostgresDSN returns the lib/pq connection url for cfg
+func PostgresDSN(cfg Config) string {
+	sslMode := cfg.SSL
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:   "/" + cfg.Name,
+	}
+
+	if cfg.Password == "" {
+		u.User = url.User(cfg.User)
+	} else {
+		u.User = url.UserPassword(cfg.User, cfg.Password)
+	}
+
+	q := url.Values{}
+	q.Set("sslmode", sslMode)
+	q.Set("timezone", "UTC")
+	q.Set("connect_timeout", fmt.Sprint(int(connectTimeout.Seconds())))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// SqliteDSN returns the go-sqlite data source name for the database file at path. Every connection waits
+// for locks rather than failing at once, and the write ahead log lets reads carry on while checks write.
+// Transactions take the write lock when they begin, so one that reads before it writes can't fail when
+// another connection commits in between. _time_format is left out: the driver ignores every parameter
+// after it, including _txlock, and its default already writes times in the sqlite format.
+func SqliteDSN(path string) string {
+	q := url.Values{}
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + q.Encode()
+}
+
+// sqliteConstraintUnique is the sqlite extended result code for a unique constraint failure
+const sqliteConstraintUnique = 2067
+
+// IsUniqueViolation returns true if err is a database refusing a row that would break a unique index
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqliteConstraintUnique
+	}
+
+	return false
+}
+
 func testDB(err error, db *sql.DB) error {
 	err = db.Ping()
 	if err != nil {
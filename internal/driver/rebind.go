@@ -0,0 +1,38 @@
+package driver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind replaces the ? placeholders in query with numbered ones when the database type needs them.
+// Question marks inside quoted strings are left alone.
+func Rebind(dbType, query string) string {
+	if dbType != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote rune
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
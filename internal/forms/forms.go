@@ -0,0 +1,100 @@
+package forms
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// MinPasswordLength is the shortest password a user can set
+const MinPasswordLength = 8
+
+// Form holds posted values and the validation errors found in them
+type Form struct {
+	url.Values
+	Errors errors
+}
+
+// errors holds validation messages by field name
+type errors map[string][]string
+
+// Add adds a message for a field
+func (e errors) Add(field, message string) {
+	e[field] = append(e[field], message)
+}
+
+// Get returns the first message for a field, or an empty string
+func (e errors) Get(field string) string {
+	if len(e[field]) == 0 {
+		return ""
+	}
+	return e[field][0]
+}
+
+// New returns a form for data, which may be nil
+func New(data url.Values) *Form {
+	if data == nil {
+		data = url.Values{}
+	}
+
+	return &Form{
+		data,
+		errors(map[string][]string{}),
+	}
+}
+
+// Valid returns true if no validation failed
+func (f *Form) Valid() bool {
+	return len(f.Errors) == 0
+}
+
+// Required checks that the fields are not blank
+func (f *Form) Required(fields ...string) {
+	for _, field := range fields {
+		if strings.TrimSpace(f.Get(field)) == "" {
+			f.Errors.Add(field, "This field cannot be blank")
+		}
+	}
+}
+
+// IsEmail checks that a field is a plain email address, if it is set
+func (f *Form) IsEmail(field string) {
+	value := strings.TrimSpace(f.Get(field))
+	if value == "" {
+		return
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		f.Errors.Add(field, "Invalid email address")
+	}
+}
+
+// IsStrongPassword checks that a field, if it is set, is at least MinPasswordLength
+// characters and mixes letters with digits or symbols
+func (f *Form) IsStrongPassword(field string) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+
+	if len([]rune(value)) < MinPasswordLength {
+		f.Errors.Add(field, fmt.Sprintf("Password must be at least %d characters", MinPasswordLength))
+		return
+	}
+
+	var letters, others bool
+	for _, c := range value {
+		if unicode.IsLetter(c) {
+			letters = true
+		} else {
+			others = true
+		}
+	}
+
+	if !letters || !others {
+		f.Errors.Add(field, "Password must contain letters and at least one number or symbol")
+	}
+}
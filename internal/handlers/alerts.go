@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"server_monitor/internal/channeldata"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"server_monitor/internal/notifiers"
+	"sync"
+)
+
+// alertMailTemplate is the mail template, in app.TemplateCache, used for status change alerts
+const alertMailTemplate = "alert.mail.tmpl"
+
+// notifications counts the notifier goroutines that are still sending an alert
+var notifications sync.WaitGroup
+
+// WaitForNotifications waits until the alerts being sent by notifiers have gone, or ctx ends
+func WaitForNotifications(ctx context.Context) error {
+	done := make(chan bool)
+	go func() {
+		notifications.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendAlerts notifies whoever is configured to hear about a host service's change from oldStatus
+func sendAlerts(h models.Host, hs models.HostService, oldStatus string) {
+	// the first check of a new service isn't news unless something is wrong
+	if oldStatus == checks.StatusPending && hs.Status == checks.StatusHealthy {
+		return
+	}
+
+	if app.Preferences.GetBool("notify_via_email") {
+		sendAlertEmail(h, hs, oldStatus)
+	}
+
+	prefs := app.Preferences.Snapshot()
+
+	alert := notifiers.Alert{
+		Identifier:  prefs["identifier"],
+		HostID:      h.ID,
+		HostName:    h.HostName,
+		ServiceName: hs.Service.ServiceName,
+		OldStatus:   oldStatus,
+		NewStatus:   hs.Status,
+		Message:     hs.LastMessage,
+		CheckedAt:   hs.LastCheck,
+	}
+
+	for _, p := range notifiers.Providers() {
+		if !p.Enabled(prefs) {
+			continue
+		}
+
+		n, err := p.New(prefs)
+		if err != nil {
+			log.Printf("Could not set up %s notifications: %s", p.Name, err)
+			continue
+		}
+
+		// don't hold up the check while the provider answers
+		notifications.Add(1)
+		go func(name string, n notifiers.Notifier) {
+			defer notifications.Done()
+			if err := n.Notify(alert); err != nil {
+				log.Printf("Could not send %s notification: %s", name, err)
+			}
+		}(p.Name, n)
+	}
+}
+
+// sendAlertEmail queues a status change alert for the mail workers
+func sendAlertEmail(h models.Host, hs models.HostService, oldStatus string) {
+	if app.Preferences.Get("notify_email") == "" {
+		log.Println("Email notifications are on, but there is no address to notify")
+		return
+	}
+
+	subject := fmt.Sprintf("%s: %s on %s is %s", app.Preferences.Get("identifier"), hs.Service.ServiceName, h.HostName, hs.Status)
+
+	helpers.SendEmail(channeldata.MailData{
+		ToName:    app.Preferences.Get("notify_name"),
+		ToAddress: app.Preferences.Get("notify_email"),
+		Subject:   subject,
+		Template:  alertMailTemplate,
+		StringMap: map[string]string{
+			"subject":      subject,
+			"host_name":    h.HostName,
+			"service_name": hs.Service.ServiceName,
+			"old_status":   oldStatus,
+			"new_status":   hs.Status,
+			"message":      hs.LastMessage,
+			"last_check":   hs.LastCheck.Format("2006-01-02 15:04:05"),
+		},
+	})
+}
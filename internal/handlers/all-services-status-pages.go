@@ -1,34 +1,46 @@
 package handlers
 
 import (
+	"github.com/CloudyKit/jet/v6"
+	"log"
 	"net/http"
+	"server_monitor/internal/checks"
 	"server_monitor/internal/helpers"
 )
 
 // AllHealthyServices lists all healthy services
 func (repo *DBRepo) AllHealthyServices(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "healthy", nil, nil)
-	if err != nil {
-		printTemplateError(w, err)
-	}
+	repo.renderServicesByStatus(w, r, checks.StatusHealthy, "healthy")
 }
 
+// AllWarningsServices lists all warning services
 func (repo *DBRepo) AllWarningsServices(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "warning", nil, nil)
-	if err != nil {
-		printTemplateError(w, err)
-	}
+	repo.renderServicesByStatus(w, r, checks.StatusWarning, "warning")
 }
 
+// AllProblemServices lists all problem services
 func (repo *DBRepo) AllProblemServices(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "problems", nil, nil)
-	if err != nil {
-		printTemplateError(w, err)
-	}
+	repo.renderServicesByStatus(w, r, checks.StatusProblem, "problems")
 }
 
+// AllPendingServices lists all pending services
 func (repo *DBRepo) AllPendingServices(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "pending", nil, nil)
+	repo.renderServicesByStatus(w, r, checks.StatusPending, "pending")
+}
+
+// renderServicesByStatus renders a page listing the monitored host services with a status
+func (repo *DBRepo) renderServicesByStatus(w http.ResponseWriter, r *http.Request, status, templateName string) {
+	services, err := repo.DB.GetServicesByStatus(status)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, templateName, vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
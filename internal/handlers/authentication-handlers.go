@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
+	"errors"
 	"fmt"
+	"github.com/go-chi/chi"
 	"log"
+	"net"
 	"net/http"
 	"server_monitor/internal/helpers"
+	"server_monitor/internal/lockout"
 	"server_monitor/internal/models"
+	"server_monitor/internal/remember"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -35,13 +39,42 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 		ClientError(w, r, http.StatusBadRequest)
 		return
 	}
-	id, hash, err := repo.DB.Authenticate(r.Form.Get("email"), r.Form.Get("password"))
+	email := strings.TrimSpace(r.Form.Get("email"))
+	ipAddress := clientIP(r)
+
+	// the attempt is recorded as failed before the password is checked, so that attempts made at the
+	// same time count against each other, and is forgotten again unless the password was wrong
+	attemptID, err := repo.DB.InsertFailedLogin(models.FailedLogin{
+		Email:       strings.ToLower(email),
+		IPAddress:   ipAddress,
+		AttemptedAt: time.Now(),
+	})
+	if err != nil {
+		ServerError(w, r, err)
+		return
+	}
+
+	wait, err := repo.loginWait(email, ipAddress, attemptID)
+	if err != nil {
+		repo.forgetLoginAttempt(attemptID)
+		ServerError(w, r, err)
+		return
+	} else if wait > 0 {
+		repo.forgetLoginAttempt(attemptID)
+		repo.renderLoginError(w, r, fmt.Sprintf("Too many failed logins, try again in %s", waitText(wait)))
+		return
+	}
+
+	id, hash, err := repo.DB.Authenticate(email, r.Form.Get("password"))
 	if err == models.ErrInvalidCredentials {
-		app.Session.Put(r.Context(), "error", "Invalid login")
-		err := helpers.RenderPage(w, r, "login", nil, nil)
-		if err != nil {
-			printTemplateError(w, err)
-		}
+		repo.renderLoginError(w, r, "Invalid login")
+		return
+	}
+
+	repo.forgetLoginAttempt(attemptID)
+
+	if err == models.ErrInactiveAccount {
+		repo.renderLoginError(w, r, "Your account is inactive, ask an administrator to activate it")
 		return
 	} else if err != nil {
 		log.Println(err)
@@ -49,36 +82,20 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Form.Get("remember") == "remember" {
-		randomString := helpers.RandomString(12)
-		hasher := sha256.New()
+	if err = repo.DB.ClearFailedLogins(strings.ToLower(email)); err != nil {
+		log.Println(err)
+	}
 
-		_, err = hasher.Write([]byte(randomString))
-		if err != nil {
-			log.Println(err)
+	if r.Form.Get("remember") == "remember" {
+		token, value, err := remember.New(id)
+		if err == nil {
+			err = repo.DB.InsertRememberToken(token)
 		}
-
-		sha := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
-
-		err = repo.DB.InsertRememberMeToken(id, sha)
 		if err != nil {
-			log.Println(err)
-		}
-
-		// write a cookie
-		expire := time.Now().Add(365 * 24 * 60 * 60 * time.Second)
-		cookie := http.Cookie{
-			Name:     fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]),
-			Value:    fmt.Sprintf("%d|%s", id, sha),
-			Path:     "/",
-			Expires:  expire,
-			HttpOnly: true,
-			Domain:   app.Domain,
-			MaxAge:   365 * 24 * 60 * 60,
-			Secure:   app.InProduction,
-			SameSite: http.SameSiteStrictMode,
+			log.Println("Could not remember login:", err)
+		} else {
+			helpers.SetRememberCookie(w, value)
 		}
-		http.SetCookie(w, &cookie)
 	}
 
 	user, err := repo.DB.GetUserById(id)
@@ -89,6 +106,7 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	app.Session.Put(r.Context(), "userID", id)
+	app.Session.Put(r.Context(), "loggedInAt", time.Now().UnixNano())
 	app.Session.Put(r.Context(), "hashedPassword", hash)
 	app.Session.Put(r.Context(), "flash", "You've been logged in successfully!")
 	app.Session.Put(r.Context(), "user", user)
@@ -101,31 +119,139 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/admin/overview", http.StatusSeeOther)
 }
 
+// renderLoginError shows the login screen again with an error
+func (repo *DBRepo) renderLoginError(w http.ResponseWriter, r *http.Request, message string) {
+	app.Session.Put(r.Context(), "error", message)
+	err := helpers.RenderPage(w, r, "login", nil, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// forgetLoginAttempt removes a login attempt recorded as failed, once it turns out not to be a wrong password
+func (repo *DBRepo) forgetLoginAttempt(attemptID int) {
+	if err := repo.DB.DeleteFailedLogin(attemptID); err != nil {
+		log.Println("Could not remove login attempt:", err)
+	}
+}
+
+// loginWait returns how long is left before a login to email from ipAddress may be tried,
+// after the failed logins to the account and from the address other than the attempt being made
+func (repo *DBRepo) loginWait(email, ipAddress string, attemptID int) (time.Duration, error) {
+	now := time.Now()
+
+	account, err := repo.DB.AccountLoginFailures(strings.ToLower(email), lockout.Account.Since(now), attemptID)
+	if err != nil {
+		return 0, err
+	}
+
+	ip, err := repo.DB.IPLoginFailures(ipAddress, lockout.IP.Since(now), attemptID)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := lockout.Account.Wait(account.Count, account.Last, now)
+	if ipWait := lockout.IP.Wait(ip.Count, ip.Last, now); ipWait > wait {
+		wait = ipWait
+	}
+
+	return wait, nil
+}
+
+// waitText describes a wait in whole seconds or minutes, rounded up
+func waitText(wait time.Duration) string {
+	if wait <= time.Minute {
+		seconds := int((wait + time.Second - 1) / time.Second)
+		if seconds == 1 {
+			return "1 second"
+		}
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+
+	return fmt.Sprintf("%d minutes", int((wait+time.Minute-1)/time.Minute))
+}
+
+// clientIP returns the IP address a request came from. A request from a trusted proxy came from the
+// last address in X-Forwarded-For that isn't a trusted proxy itself; the addresses before it can be
+// made up by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+
+		host = ip
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+
+	return host
+}
+
+// isTrustedProxy reports whether ip is one of the reverse proxies set with -trustedProxies
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, proxy := range app.TrustedProxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reasons SessionUser gives for a logged in session having ended
+var (
+	ErrUserInactive = errors.New("user is inactive")
+	ErrSignedOut    = errors.New("user was signed out everywhere")
+)
+
+// SessionUser reloads the user logged in to the request's session, and checks the session hasn't ended since:
+// the user must still exist and be active, and mustn't have been signed out everywhere after logging in.
+// A user that no longer exists gives models.ErrNoRecord.
+func (repo *DBRepo) SessionUser(r *http.Request) (models.User, error) {
+	user, err := repo.DB.GetUserById(app.Session.GetInt(r.Context(), "userID"))
+	if err != nil {
+		return user, err
+	}
+
+	if user.UserActive == 0 {
+		return user, ErrUserInactive
+	}
+
+	if !user.SignedOutAt.IsZero() && app.Session.GetInt64(r.Context(), "loggedInAt") < user.SignedOutAt.UnixNano() {
+		return user, ErrSignedOut
+	}
+
+	return user, nil
+}
+
 // Logout logs the user out
 func (repo *DBRepo) Logout(w http.ResponseWriter, r *http.Request) {
-	// delete the remember_me_token, if any
-	cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]))
-	if err == nil {
-		key := cookie.Value
-		if len(key) > 0 {
-			split := strings.Split(key, "|")
-			token := split[1]
-			err = repo.DB.DeleteToken(token)
-			if err != nil {
-				log.Println(err)
-			}
+	// delete the remember me token, if any
+	if selector, _, ok := remember.Parse(helpers.RememberCookie(r)); ok {
+		if err := repo.DB.DeleteRememberToken(selector); err != nil {
+			log.Println(err)
 		}
 	}
+	helpers.DeleteRememberCookie(w)
 
-	delCookie := http.Cookie{
-		Name:     fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]),
-		Value:    "",
-		Domain:   app.Domain,
-		Path:     "/",
-		MaxAge:   0,
-		HttpOnly: true,
-	}
-	http.SetCookie(w, &delCookie)
 	_ = app.Session.RenewToken(r.Context())
 	_ = app.Session.Destroy(r.Context())
 	_ = app.Session.RenewToken(r.Context())
@@ -133,3 +259,65 @@ func (repo *DBRepo) Logout(w http.ResponseWriter, r *http.Request) {
 	repo.App.Session.Put(r.Context(), "flash", "You've been logged out successfully!")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+// SignOutEverywhere signs a user out of every device, ending their sessions and remember me tokens
+func (repo *DBRepo) SignOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if _, err = repo.DB.GetUserById(id); err == models.ErrNoRecord {
+		ClientError(w, r, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if err = repo.DB.SignOutEverywhere(id); err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if id == app.Session.GetInt(r.Context(), "userID") {
+		// everywhere else; this session carries on
+		_ = app.Session.RenewToken(r.Context())
+		app.Session.Put(r.Context(), "loggedInAt", time.Now().UnixNano())
+		helpers.DeleteRememberCookie(w)
+		app.Session.Put(r.Context(), "flash", "You've been signed out of all your other devices")
+	} else {
+		app.Session.Put(r.Context(), "flash", "User signed out of all devices")
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/user/%d", id), http.StatusSeeOther)
+}
+
+// UnlockUser clears a user's failed logins, so they can log in again straight away
+func (repo *DBRepo) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.DB.GetUserById(id)
+	if err == models.ErrNoRecord {
+		ClientError(w, r, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if err = repo.DB.ClearFailedLogins(strings.ToLower(user.Email)); err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	app.Session.Put(r.Context(), "flash", "User unlocked")
+	http.Redirect(w, r, fmt.Sprintf("/admin/user/%d", id), http.StatusSeeOther)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"github.com/CloudyKit/jet/v6"
+	"log"
+	"net/http"
+	"net/url"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"strconv"
+	"time"
+)
+
+const eventsPerPage = 50
+
+// Events displays the events page, filtered by host, service, status and date range
+func (repo *DBRepo) Events(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := models.EventFilter{PerPage: eventsPerPage}
+	filter.HostID, _ = strconv.Atoi(q.Get("host_id"))
+	filter.ServiceID, _ = strconv.Atoi(q.Get("service_id"))
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	if status := q.Get("status"); checks.IsStatus(status) {
+		filter.Status = status
+	}
+
+	if from, err := time.ParseInLocation("2006-01-02", q.Get("from"), time.Local); err == nil {
+		filter.From = from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", q.Get("to"), time.Local); err == nil {
+		// the to date is inclusive
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	events, total, err := repo.DB.GetEvents(filter)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	services, err := repo.DB.AllServices()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	totalPages := (total + eventsPerPage - 1) / eventsPerPage
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	// the current filter, without the page, for pagination links
+	filterQuery := url.Values{}
+	for _, key := range []string{"host_id", "service_id", "status", "from", "to"} {
+		if v := q.Get(key); v != "" {
+			filterQuery.Set(key, v)
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("events", events)
+	vars.Set("hosts", hosts)
+	vars.Set("services", services)
+	vars.Set("filter", filter)
+	vars.Set("from", q.Get("from"))
+	vars.Set("to", q.Get("to"))
+	vars.Set("total", total)
+	vars.Set("page", filter.Page)
+	vars.Set("totalPages", totalPages)
+	vars.Set("filterQuery", filterQuery.Encode())
+
+	err = helpers.RenderPage(w, r, "events", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
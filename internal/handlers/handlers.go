@@ -7,13 +7,20 @@ import (
 	"log"
 	"net/http"
 	"runtime/debug"
+	"server_monitor/internal/checks"
 	"server_monitor/internal/config"
 	"server_monitor/internal/driver"
+	"server_monitor/internal/forms"
 	"server_monitor/internal/helpers"
+	"server_monitor/internal/lockout"
 	"server_monitor/internal/models"
+	"server_monitor/internal/notifiers"
+	"server_monitor/internal/preferences"
 	"server_monitor/internal/repository"
 	"server_monitor/internal/repository/dbrepo"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var Repo *DBRepo
@@ -36,23 +43,90 @@ func NewMysqlHandlers(db *driver.DB, a *config.AppConfig) *DBRepo {
 	}
 }
 
+// NewPostgresHandlers returns handlers backed by a PostgreSQL database
+func NewPostgresHandlers(db *driver.DB, a *config.AppConfig) *DBRepo {
+	return &DBRepo{
+		App: a,
+		DB:  dbrepo.NewPostgresRepo(db.SQL, a),
+	}
+}
+
+// NewSqliteHandlers returns handlers backed by a SQLite database
+func NewSqliteHandlers(db *driver.DB, a *config.AppConfig) *DBRepo {
+	return &DBRepo{
+		App: a,
+		DB:  dbrepo.NewSqliteRepo(db.SQL, a),
+	}
+}
+
+// dashboardEventCount is the number of recent events shown on the dashboard
+const dashboardEventCount = 10
+
+// failedLoginsShown is the number of recent failed logins shown on the user page
+const failedLoginsShown = 20
+
+// hostStatusSummary is the per host breakdown of service statuses shown on the dashboard
+type hostStatusSummary struct {
+	Host    *models.Host
+	Pending int
+	Healthy int
+	Warning int
+	Problem int
+}
+
 // AdminDashboard displays the dashboard
 func (repo *DBRepo) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	vars := make(jet.VarMap)
-	vars.Set("no_healthy", 0)
-	vars.Set("no_problem", 0)
-	vars.Set("no_pending", 0)
-	vars.Set("no_warning", 0)
+	pending, healthy, warning, problem, err := repo.DB.GetAllServiceStatusCounts()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
 
-	err := helpers.RenderPage(w, r, "dashboard", vars, nil)
+	hosts, err := repo.DB.AllHosts()
 	if err != nil {
-		printTemplateError(w, err)
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	var summaries []hostStatusSummary
+	for _, h := range hosts {
+		summary := hostStatusSummary{Host: h}
+		for _, hs := range h.HostServices {
+			if h.Active != 1 || hs.Active != 1 {
+				continue
+			}
+			switch hs.Status {
+			case checks.StatusHealthy:
+				summary.Healthy++
+			case checks.StatusWarning:
+				summary.Warning++
+			case checks.StatusProblem:
+				summary.Problem++
+			default:
+				summary.Pending++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	events, _, err := repo.DB.GetEvents(models.EventFilter{PerPage: dashboardEventCount})
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
 	}
-}
 
-// Events displays the events page
-func (repo *DBRepo) Events(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "events", nil, nil)
+	vars := make(jet.VarMap)
+	vars.Set("no_healthy", healthy)
+	vars.Set("no_problem", problem)
+	vars.Set("no_pending", pending)
+	vars.Set("no_warning", warning)
+	vars.Set("hosts", summaries)
+	vars.Set("events", events)
+
+	err = helpers.RenderPage(w, r, "dashboard", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -60,7 +134,10 @@ func (repo *DBRepo) Events(w http.ResponseWriter, r *http.Request) {
 
 // Settings display the settings page
 func (repo *DBRepo) Settings(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "settings", nil, nil)
+	vars := make(jet.VarMap)
+	vars.Set("defaultWebhookTemplate", notifiers.DefaultWebhookTemplate)
+
+	err := helpers.RenderPage(w, r, "settings", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -69,19 +146,19 @@ func (repo *DBRepo) Settings(w http.ResponseWriter, r *http.Request) {
 // PostSettings saves site settings
 func (repo *DBRepo) PostSettings(w http.ResponseWriter, r *http.Request) {
 	prefMap := getPreferenceMapData(r)
+	user, _ := app.Session.Get(r.Context(), "user").(models.User)
 
-	err := repo.DB.InsertOrUpdateSitePreferences(prefMap)
-	if err != nil {
+	err := app.Preferences.SetMany(prefMap, user)
+	if invalid, ok := err.(*preferences.InvalidError); ok {
+		app.Session.Put(r.Context(), "error", fmt.Sprintf("Not saved, %s", invalid))
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	} else if err != nil {
 		log.Println(err)
 		ClientError(w, r, http.StatusBadRequest)
 		return
 	}
 
-	// update app config
-	for k, v := range prefMap {
-		app.PreferenceMap[k] = v
-	}
-
 	app.Session.Put(r.Context(), "flash", "Changes saved")
 
 	if r.Form.Get("action") == "1" {
@@ -93,7 +170,17 @@ func (repo *DBRepo) PostSettings(w http.ResponseWriter, r *http.Request) {
 
 // AllHosts displays list of all hosts
 func (repo *DBRepo) AllHosts(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "hosts", nil, nil)
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("hosts", hosts)
+
+	err = helpers.RenderPage(w, r, "hosts", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -101,12 +188,262 @@ func (repo *DBRepo) AllHosts(w http.ResponseWriter, r *http.Request) {
 
 // Host shows the host add/edit form
 func (repo *DBRepo) Host(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "host", nil, nil)
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	var h models.Host
+
+	if id > 0 {
+		h, err = repo.DB.GetHostByID(id)
+		if err == models.ErrNoRecord {
+			ClientError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	serviceRows, err := repo.getHostServiceRows(h)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("host", h)
+	vars.Set("serviceRows", serviceRows)
+
+	err = helpers.RenderPage(w, r, "host", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
 }
 
+// PostHost adds/edits a host and its services
+func (repo *DBRepo) PostHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	var h models.Host
+
+	if id > 0 {
+		h, err = repo.DB.GetHostByID(id)
+		if err != nil {
+			log.Println(err)
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.HostName = strings.TrimSpace(r.Form.Get("host_name"))
+	h.CanonicalName = strings.TrimSpace(r.Form.Get("canonical_name"))
+	h.URL = strings.TrimSpace(r.Form.Get("url"))
+	h.IP = strings.TrimSpace(r.Form.Get("ip"))
+	h.IPV6 = strings.TrimSpace(r.Form.Get("ipv6"))
+	h.Location = r.Form.Get("location")
+	h.OS = r.Form.Get("os")
+	h.Active, _ = strconv.Atoi(r.Form.Get("active"))
+
+	if h.HostName == "" {
+		app.Session.Put(r.Context(), "error", "Host name is required")
+		http.Redirect(w, r, fmt.Sprintf("/admin/host/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	services, err := repo.hostServicesFromForm(h, r)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if msg := hostServiceConfigError(services); msg != "" {
+		app.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, fmt.Sprintf("/admin/host/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateHost(h)
+	} else {
+		h.ID, err = repo.DB.InsertHost(h)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	err = repo.saveHostServices(h, services)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+	repo.broadcastStatusCounts()
+
+	app.Session.Put(r.Context(), "flash", "Changes saved")
+
+	if r.Form.Get("action") == "1" {
+		http.Redirect(w, r, "/admin/host/all", http.StatusSeeOther)
+	} else {
+		http.Redirect(w, r, fmt.Sprintf("/admin/host/%d", h.ID), http.StatusSeeOther)
+	}
+}
+
+// DeleteHost deletes a host and its services
+func (repo *DBRepo) DeleteHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	h, err := repo.DB.GetHostByID(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	err = repo.DB.DeleteHost(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	for _, hs := range h.HostServices {
+		repo.removeFromMonitorMap(hs.ID)
+	}
+	repo.broadcastStatusCounts()
+
+	app.Session.Put(r.Context(), "flash", "Host deleted")
+	http.Redirect(w, r, "/admin/host/all", http.StatusSeeOther)
+}
+
+// getHostServiceRows returns one host service per available service, for the host form
+func (repo *DBRepo) getHostServiceRows(h models.Host) ([]models.HostService, error) {
+	services, err := repo.DB.AllServices()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[int]models.HostService)
+	for _, hs := range h.HostServices {
+		existing[hs.ServiceID] = hs
+	}
+
+	var rows []models.HostService
+	for _, s := range services {
+		hs, ok := existing[s.ID]
+		if !ok {
+			hs = models.HostService{
+				HostID:         h.ID,
+				ServiceID:      s.ID,
+				ScheduleNumber: 3,
+				ScheduleUnit:   "m",
+				Status:         "pending",
+				CheckConfig:    make(map[string]string),
+				Service:        *s,
+			}
+		}
+		rows = append(rows, hs)
+	}
+
+	return rows, nil
+}
+
+// hostServicesFromForm returns the services for a host with the settings from the host form
+func (repo *DBRepo) hostServicesFromForm(h models.Host, r *http.Request) ([]models.HostService, error) {
+	rows, err := repo.getHostServiceRows(h)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		hs := &rows[i]
+		hs.Active, _ = strconv.Atoi(r.Form.Get(fmt.Sprintf("service_active_%d", hs.ServiceID)))
+
+		scheduleNumber, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("schedule_number_%d", hs.ServiceID)))
+		if err == nil && scheduleNumber > 0 {
+			hs.ScheduleNumber = scheduleNumber
+		}
+
+		switch unit := r.Form.Get(fmt.Sprintf("schedule_unit_%d", hs.ServiceID)); unit {
+		case "m", "h", "d":
+			hs.ScheduleUnit = unit
+		}
+
+		if hs.CheckConfig == nil {
+			hs.CheckConfig = make(map[string]string)
+		}
+		for _, key := range checks.ConfigKeys(hs.Service.CheckType) {
+			hs.CheckConfig[key] = strings.TrimSpace(r.Form.Get(fmt.Sprintf("config_%s_%d", key, hs.ServiceID)))
+		}
+	}
+
+	return rows, nil
+}
+
+// hostServiceConfigError returns a message for the first active service whose check config isn't valid,
+// or "" if they all are
+func hostServiceConfigError(rows []models.HostService) string {
+	for _, hs := range rows {
+		if hs.Active != 1 {
+			continue
+		}
+		if err := checks.ValidateConfig(hs.Service.CheckType, hs.CheckConfig); err != nil {
+			return fmt.Sprintf("%s: %s", hs.Service.ServiceName, err)
+		}
+	}
+
+	return ""
+}
+
+// saveHostServices inserts or updates the services for a host
+func (repo *DBRepo) saveHostServices(h models.Host, rows []models.HostService) error {
+	for _, hs := range rows {
+		hs.HostID = h.ID
+		hs.HostName = h.HostName
+
+		var err error
+		if hs.ID > 0 {
+			err = repo.DB.UpdateHostService(hs)
+		} else if hs.Active == 1 {
+			hs.ID, err = repo.DB.InsertHostService(hs)
+		}
+		if err != nil {
+			return err
+		}
+
+		if hs.ID > 0 {
+			repo.updateMonitorMap(hs, h.Active == 1)
+		}
+	}
+
+	return nil
+}
+
 // AllUsers lists all admin users
 func (repo *DBRepo) AllUsers(w http.ResponseWriter, r *http.Request) {
 	vars := make(jet.VarMap)
@@ -133,22 +470,53 @@ func (repo *DBRepo) OneUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := make(jet.VarMap)
+	var user models.User
 
 	if id > 0 {
-		user, err := repo.DB.GetUserById(id)
-		if err != nil {
+		user, err = repo.DB.GetUserById(id)
+		if err == models.ErrNoRecord {
+			ClientError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
 			ClientError(w, r, http.StatusBadRequest)
 			return
 		}
-
-		vars.Set("user", user)
 	} else {
-		var u models.User
-		vars.Set("user", u)
+		user.UserActive = 1
+		user.AccessLevel = models.AccessViewer
+	}
+
+	repo.renderUserForm(w, r, user, forms.New(nil))
+}
+
+// renderUserForm renders the add/edit user page, with any validation errors in form
+func (repo *DBRepo) renderUserForm(w http.ResponseWriter, r *http.Request, user models.User, form *forms.Form) {
+	var failedLogins []models.FailedLogin
+	lockedFor := ""
+
+	if user.ID > 0 {
+		var err error
+		failedLogins, err = repo.DB.FailedLoginsForUser(user.ID, failedLoginsShown)
+		if err != nil {
+			log.Println(err)
+		}
+
+		now := time.Now()
+		account, err := repo.DB.AccountLoginFailures(strings.ToLower(user.Email), lockout.Account.Since(now), 0)
+		if err != nil {
+			log.Println(err)
+		} else if wait := lockout.Account.Wait(account.Count, account.Last, now); lockout.Account.Locked(account.Count) && wait > 0 {
+			lockedFor = waitText(wait)
+		}
 	}
 
-	err = helpers.RenderPage(w, r, "user", vars, nil)
+	vars := make(jet.VarMap)
+	vars.Set("user", user)
+	vars.Set("form", form)
+	vars.Set("failedLogins", failedLogins)
+	vars.Set("lockedFor", lockedFor)
+
+	err := helpers.RenderPage(w, r, "user", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -162,53 +530,134 @@ func (repo *DBRepo) PostOneUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = r.ParseForm()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
 	var user models.User
 
 	if id > 0 {
-		user, _ = repo.DB.GetUserById(id)
-		user.Name = r.Form.Get("name")
-		user.Email = r.Form.Get("email")
-		user.UserActive, _ = strconv.Atoi(r.Form.Get("user_active"))
-		err := repo.DB.UpdateUser(user)
-		if err != nil {
-			log.Println(err)
+		user, err = repo.DB.GetUserById(id)
+		if err == models.ErrNoRecord {
+			ClientError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
 			ClientError(w, r, http.StatusBadRequest)
 			return
 		}
+	}
+
+	self := id > 0 && id == app.Session.GetInt(r.Context(), "userID")
 
-		if len(r.Form.Get("password")) > 0 {
-			err := repo.DB.UpdatePassword(id, r.Form.Get("password"))
-			if err != nil {
-				log.Println(err)
-				ClientError(w, r, http.StatusBadRequest)
-				return
+	user.Name = strings.TrimSpace(r.Form.Get("name"))
+	user.Email = strings.TrimSpace(r.Form.Get("email"))
+
+	// users can't deactivate themselves or change their own access level,
+	// so they can't lock themselves out and there is always an admin
+	if !self {
+		user.UserActive, _ = strconv.Atoi(r.Form.Get("user_active"))
+		user.AccessLevel = accessLevelFromForm(r, models.AccessViewer)
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name", "email")
+	if id == 0 {
+		form.Required("password")
+	}
+	form.IsEmail("email")
+	form.IsStrongPassword("password")
+
+	passwordChanged := false
+
+	if form.Valid() {
+		if id > 0 {
+			err = repo.DB.UpdateUser(user)
+			if err == nil && r.Form.Get("password") != "" {
+				err = repo.DB.UpdatePassword(id, r.Form.Get("password"))
+				if err == nil {
+					// a new password ends the sessions and remember me tokens that were logged in with the old one
+					passwordChanged = true
+					err = repo.DB.SignOutEverywhere(id)
+				}
 			}
+		} else {
+			user.Password = []byte(r.Form.Get("password"))
+			_, err = repo.DB.InsertUser(user)
 		}
-	} else {
-		user.Name = r.Form.Get("name")
-		user.Email = r.Form.Get("email")
-		user.UserActive, _ = strconv.Atoi(r.Form.Get("user_active"))
-		user.Password = []byte(r.Form.Get("password"))
-		user.AccessLevel = 3
 
-		_, err := repo.DB.InsertUser(user)
-		if err != nil {
+		if err == models.ErrDuplicateEmail {
+			form.Errors.Add("email", "Another user has this email address")
+		} else if err != nil {
 			log.Println(err)
 			ClientError(w, r, http.StatusBadRequest)
 			return
 		}
 	}
 
+	if !form.Valid() {
+		repo.renderUserForm(w, r, user, form)
+		return
+	}
+
+	if self {
+		app.Session.Put(r.Context(), "user", user)
+		if passwordChanged {
+			// everywhere else; this session carries on
+			_ = app.Session.RenewToken(r.Context())
+			app.Session.Put(r.Context(), "loggedInAt", time.Now().UnixNano())
+			helpers.DeleteRememberCookie(w)
+		}
+	}
+
+	app.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
 
-// DeleteUser adds/edits a user
+// DeleteUser deletes a user, unless it is the logged in user
 func (repo *DBRepo) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
-	_ = repo.DB.DeleteUser(id)
-	repo.App.Session.Put(r.Context(), "flash", "User deleted")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if id == app.Session.GetInt(r.Context(), "userID") {
+		app.Session.Put(r.Context(), "error", "You can't delete your own account")
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+
+	err = repo.DB.DeleteUser(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	app.Session.Put(r.Context(), "flash", "User deleted")
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
 
+// accessLevelFromForm returns the access level posted with the user form, or fallback if it isn't a valid level
+func accessLevelFromForm(r *http.Request, fallback int) int {
+	level, err := strconv.Atoi(r.Form.Get("access_level"))
+	if err != nil {
+		return fallback
+	}
+
+	for _, l := range models.AccessLevels {
+		if l == level {
+			return level
+		}
+	}
+
+	return fallback
+}
+
 // getPreferenceMapData returns initialized preference data from http request
 func getPreferenceMapData(r *http.Request) map[string]string {
 	prefMap := make(map[string]string)
@@ -220,17 +669,29 @@ func getPreferenceMapData(r *http.Request) map[string]string {
 	setAData(prefMap, r, "smtp_port")
 	setAData(prefMap, r, "smtp_user")
 	setAData(prefMap, r, "smtp_password")
+	setAData(prefMap, r, "smtp_encryption")
+	setAData(prefMap, r, "smtp_auth")
+	setAData(prefMap, r, "smtp_skip_verify")
+	setAData(prefMap, r, "smtp_timeout")
 	setAData(prefMap, r, "sms_enabled")
 	setAData(prefMap, r, "sms_provider")
 	setAData(prefMap, r, "twilio_phone_number")
 	setAData(prefMap, r, "twilio_sid")
 	setAData(prefMap, r, "twilio_auth_token")
+	setAData(prefMap, r, "twilio_base_url")
 	setAData(prefMap, r, "smtp_from_email")
 	setAData(prefMap, r, "smtp_from_name")
 	setAData(prefMap, r, "notify_via_sms")
 	setAData(prefMap, r, "notify_via_email")
 	setAData(prefMap, r, "sms_notify_number")
 
+	for _, p := range notifiers.Providers() {
+		setAData(prefMap, r, p.EnabledKey())
+		for _, key := range p.Keys {
+			setAData(prefMap, r, key)
+		}
+	}
+
 	if r.Form.Get("sms_enabled") == "0" {
 		prefMap["notify_via_sms"] = "0"
 	}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/go-chi/chi"
+	"log"
+	"net/http"
+	"server_monitor/internal/models"
+	"strconv"
+)
+
+// AcknowledgeHostService marks the current status of a host service as seen by the logged in user,
+// until the status changes
+func (repo *DBRepo) AcknowledgeHostService(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	hs, err := repo.DB.GetHostServiceByID(id)
+	if err != nil {
+		log.Println(err)
+		resp.Message = "Host service not found"
+		writeJSON(w, resp)
+		return
+	}
+
+	user, _ := app.Session.Get(r.Context(), "user").(models.User)
+
+	err = repo.DB.AcknowledgeHostService(hs.ID, user.Name)
+	if err != nil {
+		resp.Message = "Could not acknowledge the service"
+		writeJSON(w, resp)
+		return
+	}
+
+	resp.OK = true
+	resp.Message = fmt.Sprintf("%s on %s acknowledged", hs.Service.ServiceName, hs.HostName)
+	writeJSON(w, resp)
+}
+
+// PauseHostService stops or restarts the checks of a host service
+func (repo *DBRepo) PauseHostService(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	hs, err := repo.DB.GetHostServiceByID(id)
+	if err != nil {
+		log.Println(err)
+		resp.Message = "Host service not found"
+		writeJSON(w, resp)
+		return
+	}
+
+	h, err := repo.DB.GetHostByID(hs.HostID)
+	if err != nil {
+		log.Println(err)
+		resp.Message = "Host not found"
+		writeJSON(w, resp)
+		return
+	}
+
+	hs.Active = 1
+	if r.PostFormValue("paused") == "1" {
+		hs.Active = 0
+	}
+
+	err = repo.DB.SetHostServiceActive(hs.ID, hs.Active)
+	if err != nil {
+		resp.Message = "Could not update the service"
+		writeJSON(w, resp)
+		return
+	}
+
+	repo.updateMonitorMap(hs, h.Active == 1)
+
+	// paused services aren't counted, so the dashboard totals change
+	repo.broadcastStatusCounts()
+
+	resp.OK = true
+	if hs.Active == 1 {
+		resp.Message = fmt.Sprintf("Checks of %s on %s resumed", hs.Service.ServiceName, hs.HostName)
+	} else {
+		resp.Message = fmt.Sprintf("Checks of %s on %s paused", hs.Service.ServiceName, hs.HostName)
+	}
+	writeJSON(w, resp)
+}
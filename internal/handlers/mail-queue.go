@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi"
+	"log"
+	"net/http"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"strconv"
+	"time"
+)
+
+const mailQueuePerPage = 50
+
+// MailQueue lists queued, sent and failed mail
+func (repo *DBRepo) MailQueue(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	status := q.Get("status")
+	switch status {
+	case models.MailQueued, models.MailSending, models.MailSent, models.MailFailed:
+	default:
+		status = ""
+	}
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	mails, total, err := repo.DB.GetQueuedMails(status, page, mailQueuePerPage)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	totalPages := (total + mailQueuePerPage - 1) / mailQueuePerPage
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("mails", mails)
+	vars.Set("status", status)
+	vars.Set("statuses", []string{models.MailQueued, models.MailSending, models.MailSent, models.MailFailed})
+	vars.Set("total", total)
+	vars.Set("page", page)
+	vars.Set("totalPages", totalPages)
+
+	err = helpers.RenderPage(w, r, "mail-queue", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// RetryMail queues a mail to be sent again straight away
+func (repo *DBRepo) RetryMail(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	m, err := repo.DB.GetQueuedMailByID(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if m.Status != models.MailQueued && m.Status != models.MailFailed {
+		app.Session.Put(r.Context(), "warning", "Only queued or failed mail can be retried")
+		http.Redirect(w, r, "/admin/mail-queue", http.StatusSeeOther)
+		return
+	}
+
+	m.Status = models.MailQueued
+	m.NextAttemptAt = time.Now()
+
+	err = repo.DB.UpdateQueuedMail(m)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	app.Session.Put(r.Context(), "flash", "Mail will be sent again shortly")
+	http.Redirect(w, r, "/admin/mail-queue?status="+r.Form.Get("status"), http.StatusSeeOther)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"server_monitor/internal/channeldata"
+	"server_monitor/internal/mailer"
+	"time"
+)
+
+// testEmailTimeout keeps the test send inside the server's write timeout
+const testEmailTimeout = 4 * time.Second
+
+// SendTestEmail sends an email right away with the SMTP settings on the settings form,
+// so they can be checked before saving, and reports the server's error if it fails
+func (repo *DBRepo) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	prefMap := getPreferenceMapData(r)
+
+	to := prefMap["notify_email"]
+	if to == "" {
+		to = prefMap["smtp_from_email"]
+	}
+	if to == "" {
+		writeJSON(w, jsonResp{Message: "Enter a notification email address to send the test to"})
+		return
+	}
+
+	msg := channeldata.MailData{
+		ToName:      prefMap["notify_name"],
+		ToAddress:   to,
+		FromName:    prefMap["smtp_from_name"],
+		FromAddress: prefMap["smtp_from_email"],
+		Subject:     fmt.Sprintf("%s: test email", app.Preferences.Get("identifier")),
+		Content:     template.HTML("<p>This is a test email. If you can read it, your mail settings work.</p>"),
+	}
+
+	err := sendMailNow(prefMap, msg)
+	if err != nil {
+		log.Println(err)
+		writeJSON(w, jsonResp{Message: fmt.Sprintf("Could not send test email: %s", err)})
+		return
+	}
+
+	writeJSON(w, jsonResp{OK: true, Message: fmt.Sprintf("Test email sent to %s", to)})
+}
+
+// sendMailNow renders msg with the default template and sends it on a new connection, bypassing the mail queue
+func sendMailNow(prefMap map[string]string, msg channeldata.MailData) error {
+	tmpl, err := app.TemplateCache.Get(msg.Template)
+	if err != nil {
+		return err
+	}
+
+	html, text, err := mailer.Render(tmpl, mailer.TemplateData{
+		Content:       msg.Content,
+		From:          msg.FromAddress,
+		FromName:      msg.FromName,
+		PreferenceMap: app.Preferences.Snapshot(),
+	})
+	if err != nil {
+		return err
+	}
+
+	settings := mailer.SMTPSettingsFromPreferences(prefMap)
+	if settings.Timeout == 0 || settings.Timeout > testEmailTimeout {
+		settings.Timeout = testEmailTimeout
+	}
+
+	var conn mailer.Connection
+	defer conn.Close()
+
+	return conn.Send(settings, mailer.NewEmail(msg, html, text))
+}
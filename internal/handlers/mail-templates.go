@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/CloudyKit/jet/v6"
+	"html/template"
+	"log"
+	"net/http"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/mailer"
+	"time"
+)
+
+// mailPreviewSample is the part of the mail template data that can be edited on the preview page
+type mailPreviewSample struct {
+	Content   string                 `json:"Content"`
+	IntMap    map[string]int         `json:"IntMap"`
+	StringMap map[string]string      `json:"StringMap"`
+	RowSets   map[string]interface{} `json:"RowSets"`
+}
+
+// defaultMailPreviewSample returns sample data that exercises the built in templates
+func defaultMailPreviewSample() mailPreviewSample {
+	return mailPreviewSample{
+		Content: "<p>This is a preview of the message content.</p>",
+		IntMap: map[string]int{
+			"healthy": 12,
+			"warning": 1,
+			"problem": 2,
+			"pending": 0,
+		},
+		StringMap: map[string]string{
+			"subject":      "observer: HTTPS on web1.example.com is problem",
+			"host_name":    "web1.example.com",
+			"service_name": "HTTPS",
+			"old_status":   "healthy",
+			"new_status":   "problem",
+			"message":      "https://web1.example.com - 503 Service Unavailable",
+			"last_check":   time.Now().Format("2006-01-02 15:04:05"),
+		},
+		RowSets: map[string]interface{}{
+			"hosts": []map[string]string{
+				{"host_name": "web1.example.com", "status": "problem"},
+				{"host_name": "db1.example.com", "status": "healthy"},
+			},
+		},
+	}
+}
+
+// MailTemplates renders a mail template with sample data, as HTML and as the plain text alternative
+func (repo *DBRepo) MailTemplates(w http.ResponseWriter, r *http.Request) {
+	names := app.TemplateCache.Names()
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = mailer.DefaultTemplate
+	}
+
+	sample := defaultMailPreviewSample()
+	sampleJSON, _ := json.MarshalIndent(sample, "", "  ")
+
+	var previewError string
+	if data := r.URL.Query().Get("data"); data != "" {
+		sampleJSON = []byte(data)
+		sample = mailPreviewSample{}
+		if err := json.Unmarshal(sampleJSON, &sample); err != nil {
+			previewError = "Sample data is not valid JSON: " + err.Error()
+		}
+	}
+
+	var html, text string
+	if previewError == "" {
+		tmpl, err := app.TemplateCache.Get(name)
+		if err == nil {
+			html, text, err = mailer.Render(tmpl, mailer.TemplateData{
+				Content:       template.HTML(sample.Content),
+				From:          app.Preferences.Get("smtp_from_email"),
+				FromName:      app.Preferences.Get("smtp_from_name"),
+				PreferenceMap: app.Preferences.Snapshot(),
+				IntMap:        sample.IntMap,
+				StringMap:     sample.StringMap,
+				RowSets:       sample.RowSets,
+			})
+		}
+		if err != nil {
+			log.Println(err)
+			previewError = err.Error()
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("names", names)
+	vars.Set("name", name)
+	vars.Set("sample", string(sampleJSON))
+	vars.Set("previewHTML", html)
+	vars.Set("previewText", text)
+	vars.Set("previewError", previewError)
+
+	err := helpers.RenderPage(w, r, "mail-templates", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/models"
+	"strings"
+	"time"
+)
+
+// ScheduledCheck performs a scheduled check on a host service by id
+func (repo *DBRepo) ScheduledCheck(hostServiceID int) {
+	hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	h, err := repo.DB.GetHostByID(hs.HostID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	result := repo.testServiceForHost(h, hs)
+
+	err = repo.updateHostServiceStatus(h, hs, result)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// updateHostServiceStatus saves the result of a check on a host service, and records an
+// event when the check moves the host service to a different status
+func (repo *DBRepo) updateHostServiceStatus(h models.Host, hs models.HostService, result checks.Result) error {
+	if !checks.IsStatus(result.Status) {
+		result.Message = fmt.Sprintf("check returned unknown status %q: %s", result.Status, result.Message)
+		result.Status = checks.StatusProblem
+	}
+
+	oldStatus := hs.Status
+
+	hs.Status = result.Status
+	hs.LastMessage = result.Message
+	hs.LastCheck = time.Now()
+	if !result.CertExpiry.IsZero() {
+		hs.CertExpiry = result.CertExpiry
+		hs.CertIssuer = result.CertIssuer
+	}
+
+	err := repo.DB.UpdateHostServiceStatus(hs)
+	if err != nil {
+		return err
+	}
+
+	broadcastChecked(hs)
+
+	if oldStatus == hs.Status {
+		return nil
+	}
+
+	// an acknowledgement is for the status it was given for
+	if !hs.AcknowledgedAt.IsZero() {
+		if err = repo.DB.ClearAcknowledgement(hs.ID); err != nil {
+			log.Println(err)
+		}
+		hs.AcknowledgedAt = time.Time{}
+		hs.AcknowledgedBy = ""
+	}
+
+	repo.broadcastStatusChanged(h, hs, oldStatus)
+	sendAlerts(h, hs, oldStatus)
+
+	_, err = repo.DB.InsertEvent(models.Event{
+		HostServiceID: hs.ID,
+		HostID:        h.ID,
+		ServiceID:     hs.ServiceID,
+		HostName:      h.HostName,
+		ServiceName:   hs.Service.ServiceName,
+		OldStatus:     oldStatus,
+		NewStatus:     hs.Status,
+		Message:       result.Message,
+		Duration:      result.Duration,
+		CreatedAt:     hs.LastCheck,
+	})
+
+	return err
+}
+
+// testServiceForHost runs the check for a host service's check type
+func (repo *DBRepo) testServiceForHost(h models.Host, hs models.HostService) checks.Result {
+	switch hs.Service.CheckType {
+	case checks.TypeHTTP:
+		return testHTTPForHost(hostURL(h, "http"), hs)
+	case checks.TypeHTTPS:
+		return testHTTPForHost(hostURL(h, "https"), hs)
+	case checks.TypeTLS:
+		return testTLSForHost(hostAddress(h), hs)
+	case checks.TypeTCP:
+		return testTCPForHost(hostAddress(h), hs)
+	}
+
+	return checks.Result{
+		Status:  hs.Status,
+		Message: fmt.Sprintf("no check available for service %s", hs.Service.ServiceName),
+	}
+}
+
+// testHTTPForHost runs an http(s) check against address with the host service's check config
+func testHTTPForHost(address string, hs models.HostService) checks.Result {
+	c, err := checks.NewHTTPConfig(address, hs.CheckConfig)
+	if err != nil {
+		return checks.Result{
+			Status:  checks.StatusProblem,
+			Message: fmt.Sprintf("invalid check configuration: %s", err),
+		}
+	}
+
+	return checks.HTTP(c)
+}
+
+// testTLSForHost runs a certificate check against host with the host service's check config
+func testTLSForHost(host string, hs models.HostService) checks.Result {
+	c, err := checks.NewTLSConfig(host, hs.CheckConfig)
+	if err != nil {
+		return checks.Result{
+			Status:  checks.StatusProblem,
+			Message: fmt.Sprintf("invalid check configuration: %s", err),
+		}
+	}
+
+	return checks.TLS(c)
+}
+
+// testTCPForHost runs a tcp port and banner check against host with the host service's check config
+func testTCPForHost(host string, hs models.HostService) checks.Result {
+	c, err := checks.NewTCPConfig(host, hs.CheckConfig)
+	if err != nil {
+		return checks.Result{
+			Status:  checks.StatusProblem,
+			Message: fmt.Sprintf("invalid check configuration: %s", err),
+		}
+	}
+
+	return checks.TCP(c)
+}
+
+// hostAddress returns the host part of the host's url, falling back to the host name
+func hostAddress(h models.Host) string {
+	u, err := url.Parse(hostURL(h, "https"))
+	if err != nil || u.Hostname() == "" {
+		return h.HostName
+	}
+	return u.Hostname()
+}
+
+// hostURL returns the host's url with the given scheme, falling back to the host name
+func hostURL(h models.Host, scheme string) string {
+	address := h.URL
+	if address == "" {
+		address = h.HostName
+	}
+
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = address[i+3:]
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, address)
+}
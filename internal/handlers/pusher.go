@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"github.com/robfig/cron/v3"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"server_monitor/internal/broadcast"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"strconv"
+)
+
+// privateChannel is the channel every logged in user subscribes to for live updates
+const privateChannel = "private-app-channel"
+
+// PusherAuth authenticates a logged in user's subscription to a private channel
+func (repo *DBRepo) PusherAuth(w http.ResponseWriter, r *http.Request) {
+	if !repo.liveUpdatesAllowed(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	params, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	response, err := app.WsClient.AuthenticatePrivateChannel(params)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(response)
+}
+
+// liveUpdatesAllowed returns true if the request comes from a session that is logged in and hasn't ended since.
+// The live update routes aren't behind the Auth middleware, so they check this themselves.
+func (repo *DBRepo) liveUpdatesAllowed(r *http.Request) bool {
+	if !helpers.IsAuthenticated(r) {
+		return false
+	}
+
+	_, err := repo.SessionUser(r)
+	return err == nil
+}
+
+// PusherHook receives webhooks from the pusher server
+func (repo *DBRepo) PusherHook(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := app.WsClient.Webhook(r.Header, body)
+	if err != nil {
+		log.Println("Invalid pusher webhook:", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	for _, event := range webhook.Events {
+		log.Printf("Pusher webhook: %s on %s", event.Name, event.Channel)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// LiveUpdates serves the built in websocket hub to a logged in user
+func (repo *DBRepo) LiveUpdates(w http.ResponseWriter, r *http.Request) {
+	hub, ok := app.Broadcaster.(*broadcast.Hub)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !repo.liveUpdatesAllowed(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Query().Get("channel") != privateChannel {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := hub.Serve(w, r, privateChannel); err != nil {
+		log.Println(err)
+	}
+}
+
+// broadcastEvent sends an event with data to every logged in user
+func broadcastEvent(event string, data map[string]string) {
+	if app.Broadcaster == nil {
+		return
+	}
+
+	err := app.Broadcaster.Trigger(privateChannel, event, data)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// broadcastStatusChanged broadcasts a host service's move from oldStatus, and the new service counts
+func (repo *DBRepo) broadcastStatusChanged(h models.Host, hs models.HostService, oldStatus string) {
+	broadcastEvent("host-service-status-changed", map[string]string{
+		"host_service_id": strconv.Itoa(hs.ID),
+		"host_id":         strconv.Itoa(h.ID),
+		"host_name":       h.HostName,
+		"service_name":    hs.Service.ServiceName,
+		"old_status":      oldStatus,
+		"new_status":      hs.Status,
+		"message":         hs.LastMessage,
+		"last_check":      hs.LastCheck.Format("2006-01-02 15:04:05"),
+	})
+
+	repo.broadcastStatusCounts()
+}
+
+// broadcastStatusCounts broadcasts the number of monitored host services in each status
+func (repo *DBRepo) broadcastStatusCounts() {
+	if app.Broadcaster == nil {
+		return
+	}
+
+	pending, healthy, warning, problem, err := repo.DB.GetAllServiceStatusCounts()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	broadcastEvent("host-service-count-changed", map[string]string{
+		"pending_count": strconv.Itoa(pending),
+		"healthy_count": strconv.Itoa(healthy),
+		"warning_count": strconv.Itoa(warning),
+		"problem_count": strconv.Itoa(problem),
+	})
+}
+
+// broadcastChecked broadcasts the result of a check on a host service
+func broadcastChecked(hs models.HostService) {
+	data := map[string]string{
+		"host_service_id": strconv.Itoa(hs.ID),
+		"status":          hs.Status,
+		"message":         hs.LastMessage,
+		"last_check":      hs.LastCheck.Format("2006-01-02 15:04:05"),
+	}
+
+	if entryID, ok := app.MonitorMap.Get(hs.ID); ok {
+		data["next_run"] = app.Scheduler.Entry(entryID).Next.Format("2006-01-02 15:04:05")
+	}
+
+	broadcastEvent("host-service-checked", data)
+}
+
+// broadcastScheduleChanged broadcasts a new or changed schedule entry for a host service
+func broadcastScheduleChanged(hs models.HostService, entryID cron.EntryID) {
+	entry := app.Scheduler.Entry(entryID)
+
+	previous := "Pending..."
+	if !entry.Prev.IsZero() {
+		previous = entry.Prev.Format("2006-01-02 15:04:05")
+	} else if !hs.LastCheck.IsZero() {
+		previous = hs.LastCheck.Format("2006-01-02 15:04:05")
+	}
+
+	broadcastEvent("schedule-changed", map[string]string{
+		"host_service_id": strconv.Itoa(hs.ID),
+		"host":            hs.HostName,
+		"service":         hs.Service.ServiceName,
+		"schedule":        scheduleSpec(hs),
+		"previous_run":    previous,
+		"next_run":        entry.Next.Format("2006-01-02 15:04:05"),
+	})
+}
+
+// broadcastScheduleRemoved broadcasts that a host service is no longer scheduled
+func broadcastScheduleRemoved(hostServiceID int) {
+	broadcastEvent("schedule-item-removed", map[string]string{
+		"host_service_id": strconv.Itoa(hostServiceID),
+	})
+}
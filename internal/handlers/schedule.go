@@ -1,14 +1,94 @@
 package handlers
 
 import (
+	"encoding/json"
+	"github.com/CloudyKit/jet/v6"
+	"log"
 	"net/http"
 	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"sort"
 )
 
 // ListEntries lists schedule entries
 func (repo *DBRepo) ListEntries(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "schedule", nil, nil)
+	var items []models.Schedule
+
+	for hostServiceID, entryID := range app.MonitorMap.Snapshot() {
+		hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		items = append(items, models.Schedule{
+			ID:            hostServiceID,
+			EntryID:       entryID,
+			Entry:         app.Scheduler.Entry(entryID),
+			Host:          hs.HostName,
+			Service:       hs.Service.ServiceName,
+			LastRunFromHS: hs.LastCheck,
+			HostServiceID: hs.ID,
+			ScheduleText:  scheduleSpec(hs),
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Host != items[j].Host {
+			return items[i].Host < items[j].Host
+		}
+		return items[i].Service < items[j].Service
+	})
+
+	vars := make(jet.VarMap)
+	vars.Set("items", items)
+
+	err := helpers.RenderPage(w, r, "schedule", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
 }
+
+type jsonResp struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// ToggleMonitoring turns monitoring of all host services on or off
+func (repo *DBRepo) ToggleMonitoring(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+
+	enabled := r.PostFormValue("enabled") == "1"
+	user, _ := app.Session.Get(r.Context(), "user").(models.User)
+
+	err := app.Preferences.SetBool("monitoring_live", enabled, user)
+	if err != nil {
+		log.Println(err)
+		resp.Message = "Could not save monitoring preference"
+		writeJSON(w, resp)
+		return
+	}
+
+	// WatchPreferences starts or stops the checks
+	if enabled {
+		resp.Message = "Monitoring is on"
+	} else {
+		resp.Message = "Monitoring is off"
+	}
+
+	resp.OK = true
+	writeJSON(w, resp)
+}
+
+// writeJSON writes v to the response as json
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	out, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi"
+	"log"
+	"net/http"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/helpers"
+	"server_monitor/internal/models"
+	"strconv"
+	"strings"
+)
+
+// AllServices lists the services hosts can be monitored for
+func (repo *DBRepo) AllServices(w http.ResponseWriter, r *http.Request) {
+	services, err := repo.DB.AllServices()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, "services", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneService shows the service add/edit form
+func (repo *DBRepo) OneService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	s := models.Service{Active: 1, CheckType: checks.TypeHTTP}
+
+	if id > 0 {
+		s, err = repo.DB.GetServiceByID(id)
+		if err == models.ErrNoRecord {
+			ClientError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("service", s)
+	vars.Set("checkTypes", checks.Types)
+
+	err = helpers.RenderPage(w, r, "service", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostService adds/edits a service
+func (repo *DBRepo) PostService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	var s models.Service
+
+	if id > 0 {
+		s, err = repo.DB.GetServiceByID(id)
+		if err != nil {
+			log.Println(err)
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.ServiceName = strings.TrimSpace(r.Form.Get("service_name"))
+	s.CheckType = r.Form.Get("check_type")
+	s.Icon = strings.TrimSpace(r.Form.Get("icon"))
+	s.Active, _ = strconv.Atoi(r.Form.Get("active"))
+
+	if s.ServiceName == "" {
+		app.Session.Put(r.Context(), "error", "Service name is required")
+		http.Redirect(w, r, fmt.Sprintf("/admin/service/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	if !checks.IsType(s.CheckType) {
+		app.Session.Put(r.Context(), "error", "Unknown check type")
+		http.Redirect(w, r, fmt.Sprintf("/admin/service/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateService(s)
+	} else {
+		s.ID, err = repo.DB.InsertService(s)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if id > 0 {
+		repo.updateServiceSchedules(s.ID)
+	}
+
+	app.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/services", http.StatusSeeOther)
+}
+
+// DeleteService deletes a service and stops checking hosts for it
+func (repo *DBRepo) DeleteService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// deleted host services are no longer listed, so find them first
+	hostServices, err := repo.hostServicesForService(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	err = repo.DB.DeleteService(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	for _, hs := range hostServices {
+		repo.removeFromMonitorMap(hs.ID)
+	}
+
+	app.Session.Put(r.Context(), "flash", "Service deleted")
+	http.Redirect(w, r, "/admin/services", http.StatusSeeOther)
+}
+
+// updateServiceSchedules schedules or unschedules the checks of every host service using a service,
+// after the service is switched on or off
+func (repo *DBRepo) updateServiceSchedules(serviceID int) {
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, h := range hosts {
+		for _, hs := range h.HostServices {
+			if hs.ServiceID == serviceID {
+				repo.updateMonitorMap(hs, h.Active == 1)
+			}
+		}
+	}
+}
+
+// hostServicesForService returns the host services using a service, on every host
+func (repo *DBRepo) hostServicesForService(serviceID int) ([]models.HostService, error) {
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var hostServices []models.HostService
+	for _, h := range hosts {
+		for _, hs := range h.HostServices {
+			if hs.ServiceID == serviceID {
+				hostServices = append(hostServices, hs)
+			}
+		}
+	}
+
+	return hostServices, nil
+}
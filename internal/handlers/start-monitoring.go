@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"log"
+	"server_monitor/internal/models"
+)
+
+// job is the unit of work run by the scheduler for one host service
+type job struct {
+	HostServiceID int
+}
+
+// Run runs the scheduled check for a host service
+func (j job) Run() {
+	Repo.ScheduledCheck(j.HostServiceID)
+}
+
+// StartMonitoring registers a scheduler entry for every active host service, if monitoring is live
+func (repo *DBRepo) StartMonitoring() {
+	if !app.Preferences.GetBool("monitoring_live") {
+		return
+	}
+
+	servicesToMonitor, err := repo.DB.GetServicesToMonitor()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, hs := range servicesToMonitor {
+		repo.addToMonitorMap(hs)
+	}
+}
+
+// StopMonitoring removes every scheduler entry
+func (repo *DBRepo) StopMonitoring() {
+	var removed []int
+	app.MonitorMap.Clear(func(hostServiceID int, entryID cron.EntryID) {
+		app.Scheduler.Remove(entryID)
+		removed = append(removed, hostServiceID)
+	})
+
+	for _, hostServiceID := range removed {
+		broadcastScheduleRemoved(hostServiceID)
+	}
+}
+
+// WatchPreferences starts or stops monitoring whenever the monitoring_live preference changes.
+// Call the returned function to stop watching.
+func (repo *DBRepo) WatchPreferences() func() {
+	changes, unsubscribe := app.Preferences.Subscribe("monitoring_live")
+
+	go func() {
+		for c := range changes {
+			if c.New == "1" {
+				log.Println("Monitoring turned on by", c.User.Name)
+				repo.StartMonitoring()
+			} else {
+				log.Println("Monitoring turned off by", c.User.Name)
+				repo.StopMonitoring()
+			}
+		}
+	}()
+
+	return unsubscribe
+}
+
+// updateMonitorMap re-registers the scheduler entry for a host service, or removes it if it should not run
+func (repo *DBRepo) updateMonitorMap(hs models.HostService, hostActive bool) {
+	if hostActive && hs.Active == 1 && hs.Service.Active == 1 {
+		repo.addToMonitorMap(hs)
+	} else {
+		repo.removeFromMonitorMap(hs.ID)
+	}
+}
+
+// addToMonitorMap adds a scheduler entry for a host service if monitoring is live, replacing any entry the
+// host service had. Monitoring is checked with the monitor map locked, so an entry can't be added after
+// StopMonitoring has cleared the map.
+func (repo *DBRepo) addToMonitorMap(hs models.HostService) {
+	var added cron.EntryID
+	removed := false
+
+	app.MonitorMap.Update(hs.ID, func(old cron.EntryID, ok bool) (cron.EntryID, bool) {
+		if ok {
+			app.Scheduler.Remove(old)
+			removed = true
+		}
+
+		if !app.Preferences.GetBool("monitoring_live") {
+			return 0, false
+		}
+
+		entryID, err := app.Scheduler.AddJob(scheduleSpec(hs), job{HostServiceID: hs.ID})
+		if err != nil {
+			log.Println(err)
+			return 0, false
+		}
+
+		added = entryID
+		return entryID, true
+	})
+
+	if added != 0 {
+		broadcastScheduleChanged(hs, added)
+	} else if removed {
+		broadcastScheduleRemoved(hs.ID)
+	}
+}
+
+// removeFromMonitorMap removes the scheduler entry for a host service, if any
+func (repo *DBRepo) removeFromMonitorMap(hostServiceID int) {
+	removed := false
+
+	app.MonitorMap.Update(hostServiceID, func(entryID cron.EntryID, ok bool) (cron.EntryID, bool) {
+		if ok {
+			app.Scheduler.Remove(entryID)
+			removed = true
+		}
+		return 0, false
+	})
+
+	if removed {
+		broadcastScheduleRemoved(hostServiceID)
+	}
+}
+
+// scheduleSpec returns the cron spec for a host service's check interval
+func scheduleSpec(hs models.HostService) string {
+	number, unit := hs.ScheduleNumber, hs.ScheduleUnit
+	if number < 1 {
+		number = 1
+	}
+
+	// time.ParseDuration, used by @every, has no unit for days
+	if unit == "d" {
+		number, unit = number*24, "h"
+	}
+
+	return fmt.Sprintf("@every %d%s", number, unit)
+}
@@ -1,25 +1,20 @@
 package helpers
 
 import (
+	"crypto/rand"
 	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/justinas/nosurf"
 	"log"
-	"math/rand"
+	"math/big"
 	"net/http"
 	"runtime/debug"
 	"server_monitor/internal/config"
 	"server_monitor/internal/models"
 	"server_monitor/internal/templates"
-	"time"
 )
 
-const (
-	letterBytes   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	letterIdxBits = 6                    // 6 bits to represent a letter index
-	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
-)
+const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 // views is the jet template set
 var views = jet.NewSet(
@@ -28,7 +23,6 @@ var views = jet.NewSet(
 )
 
 var app *config.AppConfig
-var src = rand.NewSource(time.Now().UnixNano())
 
 // NewHelpers creates new helpers
 func NewHelpers(a *config.AppConfig) {
@@ -41,22 +35,18 @@ func IsAuthenticated(r *http.Request) bool {
 	return exists
 }
 
-// RandomString returns a random string of letters of length n
-// TODO:
-// 		Understand how this function works
+// RandomString returns a random string of letters of length n, read from crypto/rand
 func RandomString(n int) string {
 	b := make([]byte, n)
+	max := big.NewInt(int64(len(letterBytes)))
 
-	for i, theCache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			theCache, remain = src.Int63(), letterIdxMax
-		}
-		if idx := int(theCache & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i--
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			// crypto/rand only fails if the system has no source of randomness
+			panic(err)
 		}
-		theCache >>= letterIdxBits
-		remain--
+		b[i] = letterBytes[idx.Int64()]
 	}
 
 	return string(b)
@@ -78,7 +68,7 @@ func ServerError(w http.ResponseWriter, r *http.Request, err error) {
 func DefaultData(td templates.TemplateData, r *http.Request, w http.ResponseWriter) templates.TemplateData {
 	td.CSRFToken = nosurf.Token(r)
 	td.IsAuthenticated = IsAuthenticated(r)
-	td.PreferenceMap = app.PreferenceMap
+	td.PreferenceMap = app.Preferences.Snapshot()
 
 	// if logged in, store user id in template data
 	if td.IsAuthenticated {
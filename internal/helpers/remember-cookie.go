@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"net/http"
+	"server_monitor/internal/remember"
+	"time"
+)
+
+// SetRememberCookie writes the remember me cookie
+func SetRememberCookie(w http.ResponseWriter, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     remember.CookieName(app.Identifier),
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(remember.Lifetime),
+		MaxAge:   int(remember.Lifetime.Seconds()),
+		HttpOnly: true,
+		Domain:   app.Domain,
+		Secure:   app.InProduction,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// RememberCookie returns the value of the remember me cookie, or "" if there isn't one
+func RememberCookie(r *http.Request) string {
+	cookie, err := r.Cookie(remember.CookieName(app.Identifier))
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// DeleteRememberCookie tells the browser to delete the remember me cookie
+func DeleteRememberCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     remember.CookieName(app.Identifier),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Domain:   app.Domain,
+		Secure:   app.InProduction,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -1,6 +1,9 @@
 package helpers
 
-import "time"
+import (
+	"server_monitor/internal/models"
+	"time"
+)
 
 func addTemplateFunctions() {
 	views.AddGlobal("humanDate", func(t time.Time) string {
@@ -14,6 +17,24 @@ func addTemplateFunctions() {
 	views.AddGlobal("dateAfterYearOne", func(t time.Time) bool {
 		return DateAfterY1(t)
 	})
+
+	views.AddGlobal("statusClass", func(status string) string {
+		return StatusClass(status)
+	})
+
+	views.AddGlobal("canOperate", func(u models.User) bool {
+		return u.HasAccess(models.AccessOperator)
+	})
+
+	views.AddGlobal("isAdmin", func(u models.User) bool {
+		return u.HasAccess(models.AccessAdmin)
+	})
+
+	views.AddGlobal("accessLevels", models.AccessLevels)
+
+	views.AddGlobal("accessLevelName", func(level int) string {
+		return models.AccessLevelName(level)
+	})
 }
 
 // HumanDate formats a time in yyyy-MM-dd format
@@ -34,3 +55,17 @@ func DateAfterY1(t time.Time) bool {
 	yearOne := time.Date(0001, 11, 17, 20, 34, 58, 651387237, time.UTC)
 	return t.After(yearOne)
 }
+
+// StatusClass returns the bootstrap colour class used to display a host service status
+func StatusClass(status string) string {
+	switch status {
+	case "healthy":
+		return "success"
+	case "warning":
+		return "warning"
+	case "problem":
+		return "danger"
+	default:
+		return "secondary"
+	}
+}
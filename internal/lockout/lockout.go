@@ -0,0 +1,73 @@
+package lockout
+
+import "time"
+
+// Policy says how many failed logins are allowed before further attempts have to wait, and when to lock out
+type Policy struct {
+	// Window is how far back failed logins count
+	Window time.Duration
+	// FreeAttempts failures may be made without waiting
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure past FreeAttempts; it doubles with each one after that,
+	// up to Lockout
+	BaseDelay time.Duration
+	// MaxFailures failures lock logins out for Lockout after the last one
+	MaxFailures int
+	Lockout     time.Duration
+}
+
+// Account limits failed logins to one email address
+var Account = Policy{
+	Window:       15 * time.Minute,
+	FreeAttempts: 2,
+	BaseDelay:    2 * time.Second,
+	MaxFailures:  5,
+	Lockout:      15 * time.Minute,
+}
+
+// IP limits failed logins from one address, to any account. It is looser than Account,
+// since people behind the same address may share it.
+var IP = Policy{
+	Window:       15 * time.Minute,
+	FreeAttempts: 10,
+	BaseDelay:    time.Second,
+	MaxFailures:  30,
+	Lockout:      15 * time.Minute,
+}
+
+// Since returns when the policy's window starts, at now
+func (p Policy) Since(now time.Time) time.Time {
+	return now.Add(-p.Window)
+}
+
+// Locked reports whether failures lock logins out, rather than only delaying them
+func (p Policy) Locked(failures int) bool {
+	return failures >= p.MaxFailures
+}
+
+// Wait returns how long is left before another login may be tried after failures, the last at last.
+// It is zero if a login may be tried now.
+func (p Policy) Wait(failures int, last, now time.Time) time.Duration {
+	var delay time.Duration
+
+	switch {
+	case p.Locked(failures):
+		delay = p.Lockout
+	case failures > p.FreeAttempts:
+		delay = p.BaseDelay
+		for n := p.FreeAttempts + 1; n < failures && delay < p.Lockout; n++ {
+			delay *= 2
+		}
+		if delay > p.Lockout {
+			delay = p.Lockout
+		}
+	default:
+		return 0
+	}
+
+	if wait := last.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
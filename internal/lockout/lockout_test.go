@@ -0,0 +1,122 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+// testPolicy allows two free attempts, then waits 2s, 4s and 8s, and locks out at the sixth failure
+var testPolicy = Policy{
+	Window:       15 * time.Minute,
+	FreeAttempts: 2,
+	BaseDelay:    2 * time.Second,
+	MaxFailures:  6,
+	Lockout:      15 * time.Minute,
+}
+
+// slowPolicy doubles its delay past the length of its lockout long before it locks out
+var slowPolicy = Policy{
+	Window:       15 * time.Minute,
+	FreeAttempts: 1,
+	BaseDelay:    time.Minute,
+	MaxFailures:  100,
+	Lockout:      15 * time.Minute,
+}
+
+func TestWait(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		failures int
+		last     time.Time
+		want     time.Duration
+	}{
+		{"no failures", 0, time.Time{}, 0},
+		{"first free attempt", 1, now, 0},
+		{"last free attempt", 2, now, 0},
+		{"first delay", 3, now, 2 * time.Second},
+		{"delay doubles", 4, now, 4 * time.Second},
+		{"delay doubles again", 5, now, 8 * time.Second},
+		{"part of the delay has passed", 4, now.Add(-time.Second), 3 * time.Second},
+		{"delay has passed", 5, now.Add(-8 * time.Second), 0},
+		{"locked out at max failures", 6, now, 15 * time.Minute},
+		{"still locked out past max failures", 9, now.Add(-10 * time.Minute), 5 * time.Minute},
+		{"lockout has passed", 6, now.Add(-15 * time.Minute), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testPolicy.Wait(tt.failures, tt.last, now); got != tt.want {
+				t.Errorf("Wait(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitIsNoLongerThanLockout(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{6, 15 * time.Minute},
+		{70, 15 * time.Minute},
+		{99, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := slowPolicy.Wait(tt.failures, now, now); got != tt.want {
+			t.Errorf("Wait(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLocked(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     bool
+	}{
+		{0, false},
+		{5, false},
+		{6, true},
+		{7, true},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.Locked(tt.failures); got != tt.want {
+			t.Errorf("Locked(%d) = %t, want %t", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestSince(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	if got, want := testPolicy.Since(now), now.Add(-15*time.Minute); !got.Equal(want) {
+		t.Errorf("Since = %s, want %s", got, want)
+	}
+}
+
+// TestPolicies checks the policies in use give some free attempts and delay before they lock out
+func TestPolicies(t *testing.T) {
+	for name, p := range map[string]Policy{"Account": Account, "IP": IP} {
+		now := time.Now()
+
+		if p.Wait(p.FreeAttempts, now, now) != 0 {
+			t.Errorf("%s: a wait after %d failures, want them free", name, p.FreeAttempts)
+		}
+		if p.FreeAttempts+1 < p.MaxFailures && p.Wait(p.FreeAttempts+1, now, now) != p.BaseDelay {
+			t.Errorf("%s: the first delay isn't BaseDelay", name)
+		}
+		if !p.Locked(p.MaxFailures) || p.Wait(p.MaxFailures, now, now) != p.Lockout {
+			t.Errorf("%s: not locked out for Lockout at %d failures", name, p.MaxFailures)
+		}
+		if p.Wait(p.MaxFailures-1, now, now) > p.Lockout {
+			t.Errorf("%s: the delay before lockout is longer than the lockout", name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/aymerick/douceur/inliner"
+	"html/template"
+	"io/fs"
+	"jaytaylor.com/html2text"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"sync"
+)
+
+// DefaultTemplate is used for mail that doesn't name a template
+const DefaultTemplate = "bootstrap.mail.tmpl"
+
+// templateSuffix is the file name suffix of a mail template
+const templateSuffix = ".mail.tmpl"
+
+//go:embed templates/*.mail.tmpl
+var embeddedTemplates embed.FS
+
+// TemplateData holds the data passed to a mail template
+type TemplateData struct {
+	Content       template.HTML
+	From          string
+	FromName      string
+	PreferenceMap map[string]string
+	IntMap        map[string]int
+	StringMap     map[string]string
+	FloatMap      map[string]float32
+	RowSets       map[string]interface{}
+}
+
+// TemplateCache holds the parsed mail templates
+type TemplateCache struct {
+	fsys      fs.FS
+	reload    bool
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+// NewTemplateCache parses the mail templates in dir, or the embedded templates if dir is empty.
+// If reload is true the templates are parsed again on every lookup, so edits show without a restart.
+func NewTemplateCache(dir string, reload bool) (*TemplateCache, error) {
+	tc := &TemplateCache{reload: reload}
+
+	if dir == "" {
+		sub, err := fs.Sub(embeddedTemplates, "templates")
+		if err != nil {
+			return nil, err
+		}
+		tc.fsys = sub
+	} else {
+		tc.fsys = os.DirFS(dir)
+	}
+
+	templates, err := parseTemplates(tc.fsys)
+	if err != nil {
+		return nil, err
+	}
+	tc.templates = templates
+
+	return tc, nil
+}
+
+// parseTemplates parses every mail template in fsys, keyed by file name
+func parseTemplates(fsys fs.FS) (map[string]*template.Template, error) {
+	pages, err := fs.Glob(fsys, "*"+templateSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template)
+
+	for _, page := range pages {
+		name := path.Base(page)
+
+		tmpl, err := template.New(name).ParseFS(fsys, page)
+		if err != nil {
+			return nil, err
+		}
+
+		templates[name] = tmpl
+	}
+
+	return templates, nil
+}
+
+// refresh parses the templates again if the cache reloads
+func (tc *TemplateCache) refresh() error {
+	if !tc.reload {
+		return nil
+	}
+
+	templates, err := parseTemplates(tc.fsys)
+	if err != nil {
+		return err
+	}
+
+	tc.mu.Lock()
+	tc.templates = templates
+	tc.mu.Unlock()
+
+	return nil
+}
+
+// Get returns the named template, or DefaultTemplate if name is empty
+func (tc *TemplateCache) Get(name string) (*template.Template, error) {
+	if name == "" {
+		name = DefaultTemplate
+	}
+
+	if err := tc.refresh(); err != nil {
+		return nil, err
+	}
+
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	tmpl, ok := tc.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("could not get mail template %s", name)
+	}
+
+	return tmpl, nil
+}
+
+// Names returns the names of the cached templates, sorted
+func (tc *TemplateCache) Names() []string {
+	if err := tc.refresh(); err != nil {
+		log.Println(err)
+	}
+
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	var names []string
+	for name := range tc.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Render executes tmpl with td, and returns the HTML with its styles inlined and a plain text alternative
+func Render(tmpl *template.Template, td TemplateData) (string, string, error) {
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, td); err != nil {
+		return "", "", err
+	}
+	html := buff.String()
+
+	// the plain text is built before inlining, so it isn't cluttered with styles
+	text, err := html2text.FromString(html, html2text.Options{PrettyTables: true})
+	if err != nil {
+		text = ""
+	}
+
+	formatted, err := inliner.Inline(html)
+	if err != nil {
+		formatted = html
+	}
+
+	return formatted, text, nil
+}
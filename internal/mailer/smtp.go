@@ -0,0 +1,241 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	mail "github.com/xhit/go-simple-mail/v2"
+	"io"
+	"net"
+	"net/textproto"
+	"server_monitor/internal/channeldata"
+	"strconv"
+	"time"
+)
+
+// SMTP encryption settings
+const (
+	EncryptionNone     = "none"
+	EncryptionSSL      = "ssl"
+	EncryptionSTARTTLS = "starttls"
+)
+
+// SMTP authentication settings
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "crammd5"
+)
+
+// SMTPSettings holds how to connect to the SMTP server
+type SMTPSettings struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string
+	Auth       string
+	SkipVerify bool
+	// Timeout limits connecting and sending; zero means defaultTimeout
+	Timeout time.Duration
+}
+
+// defaultTimeout is how long to wait for the server when the settings don't say
+const defaultTimeout = 10 * time.Second
+
+// isLocal returns true for a server on this machine, usually a relay or a stand in like MailHog
+func isLocal(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// SMTPSettingsFromPreferences reads the smtp_* preferences. Without an encryption or auth
+// setting, local servers get plain SMTP with PLAIN auth and others STARTTLS with LOGIN auth.
+func SMTPSettingsFromPreferences(prefs map[string]string) SMTPSettings {
+	port, _ := strconv.Atoi(prefs["smtp_port"])
+
+	s := SMTPSettings{
+		Host:       prefs["smtp_server"],
+		Port:       port,
+		Username:   prefs["smtp_user"],
+		Password:   prefs["smtp_password"],
+		Encryption: prefs["smtp_encryption"],
+		Auth:       prefs["smtp_auth"],
+		SkipVerify: prefs["smtp_skip_verify"] == "1",
+	}
+
+	if timeout, err := time.ParseDuration(prefs["smtp_timeout"]); err == nil {
+		s.Timeout = timeout
+	}
+
+	if s.Encryption == "" {
+		s.Encryption = EncryptionSTARTTLS
+		if isLocal(s.Host) {
+			s.Encryption = EncryptionNone
+		}
+	}
+
+	if s.Auth == "" {
+		s.Auth = AuthLogin
+		if isLocal(s.Host) {
+			s.Auth = AuthPlain
+		}
+	}
+
+	return s
+}
+
+// Server returns a mail server for the settings
+func (s SMTPSettings) Server(keepAlive bool) (*mail.SMTPServer, error) {
+	server := mail.NewSMTPClient()
+
+	server.Host = s.Host
+	server.Port = s.Port
+	server.Username = s.Username
+	server.Password = s.Password
+	server.KeepAlive = keepAlive
+	server.ConnectTimeout = defaultTimeout
+	server.SendTimeout = defaultTimeout
+	if s.Timeout > 0 {
+		server.ConnectTimeout = s.Timeout
+		server.SendTimeout = s.Timeout
+	}
+	server.TLSConfig = &tls.Config{
+		ServerName:         s.Host,
+		InsecureSkipVerify: s.SkipVerify,
+	}
+
+	switch s.Encryption {
+	case EncryptionNone:
+		server.Encryption = mail.EncryptionNone
+	case EncryptionSSL:
+		server.Encryption = mail.EncryptionSSLTLS
+	case EncryptionSTARTTLS:
+		server.Encryption = mail.EncryptionSTARTTLS
+	default:
+		return nil, fmt.Errorf("unknown smtp encryption %q", s.Encryption)
+	}
+
+	switch s.Auth {
+	case AuthPlain:
+		server.Authentication = mail.AuthPlain
+	case AuthLogin:
+		server.Authentication = mail.AuthLogin
+	case AuthCRAMMD5:
+		server.Authentication = mail.AuthCRAMMD5
+	default:
+		return nil, fmt.Errorf("unknown smtp auth %q", s.Auth)
+	}
+
+	return server, nil
+}
+
+// NewEmail builds an email for mailMessage with an HTML body and a plain text alternative
+func NewEmail(mailMessage channeldata.MailData, html, text string) *mail.Email {
+	email := mail.NewMSG()
+	email.SetFrom(mailMessage.FromAddress).
+		AddTo(mailMessage.ToAddress).
+		SetSubject(mailMessage.Subject)
+
+	for _, x := range mailMessage.AdditionalTo {
+		email.AddTo(x)
+	}
+	for _, x := range mailMessage.CC {
+		email.AddCc(x)
+	}
+	for _, x := range mailMessage.Attachments {
+		email.AddAttachment(x)
+	}
+
+	email.SetBody(mail.TextHTML, html)
+	email.AddAlternative(mail.TextPlain, text)
+
+	return email
+}
+
+// Connection is an SMTP connection that is kept open between messages.
+// It reconnects when the server drops it or the settings change. It is not safe for concurrent use.
+type Connection struct {
+	settings SMTPSettings
+	client   *mail.SMTPClient
+}
+
+// Send sends email using settings, reusing the open connection if there is one
+func (c *Connection) Send(settings SMTPSettings, email *mail.Email) error {
+	if c.client != nil && c.settings != settings {
+		c.Close()
+	}
+
+	reused := c.client != nil
+	if err := c.connect(settings); err != nil {
+		return err
+	}
+
+	err := email.Send(c.client)
+	if reused && connectionLost(err) {
+		// the server closed the idle connection, so try once more on a new one. A reply refusing the
+		// message isn't retried, as it would only be refused again.
+		c.Close()
+		if err = c.connect(settings); err != nil {
+			return err
+		}
+		err = email.Send(c.client)
+	}
+
+	if err != nil {
+		c.Close()
+	}
+
+	return err
+}
+
+// connectionLost returns true if err means the connection is gone, rather than the server refusing the message
+func connectionLost(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// 421 is the server saying it is closing the connection, as many do to idle clients
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code == 421
+}
+
+// connect opens a connection unless one is already open
+func (c *Connection) connect(settings SMTPSettings) error {
+	if c.client != nil {
+		return nil
+	}
+
+	server, err := settings.Server(true)
+	if err != nil {
+		return err
+	}
+
+	client, err := server.Connect()
+	if err != nil {
+		return err
+	}
+
+	c.settings = settings
+	c.client = client
+
+	return nil
+}
+
+// Close says goodbye to the server and closes the connection, if it is open
+func (c *Connection) Close() {
+	if c.client == nil {
+		return
+	}
+
+	_ = c.client.Quit()
+	_ = c.client.Close()
+	c.client = nil
+}
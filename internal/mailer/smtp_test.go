@@ -0,0 +1,583 @@
+package mailer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	mail "github.com/xhit/go-simple-mail/v2"
+	"math/big"
+	"net"
+	"net/textproto"
+	"server_monitor/internal/channeldata"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSMTPUser     = "observer"
+	testSMTPPassword = "s3cret"
+	// testRejectedAddress is a recipient the test server refuses
+	testRejectedAddress = "nobody@example.com"
+)
+
+// smtpSession is what the test server saw on one connection
+type smtpSession struct {
+	tls      bool
+	authMech string
+	quit     bool
+	rcpts    int
+	messages []string
+}
+
+// smtpTestServer is a small SMTP server on a loopback port. It offers STARTTLS, or TLS from the start
+// with implicitTLS, and AUTH PLAIN, LOGIN and CRAM-MD5 for testSMTPUser.
+type smtpTestServer struct {
+	ln          net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	sessions []*smtpSession
+}
+
+func newSMTPTestServer(t *testing.T, implicitTLS bool) *smtpTestServer {
+	t.Helper()
+
+	s := &smtpTestServer{
+		tlsConfig:   &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}},
+		implicitTLS: implicitTLS,
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ln = ln
+	t.Cleanup(func() {
+		_ = ln.Close()
+		s.drop()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+// settings returns settings for the server with encryption and auth
+func (s *smtpTestServer) settings(encryption, auth string) SMTPSettings {
+	return SMTPSettings{
+		Host:       "127.0.0.1",
+		Port:       s.ln.Addr().(*net.TCPAddr).Port,
+		Username:   testSMTPUser,
+		Password:   testSMTPPassword,
+		Encryption: encryption,
+		Auth:       auth,
+		SkipVerify: true,
+		Timeout:    5 * time.Second,
+	}
+}
+
+// drop closes every open connection, as a server timing out idle clients does
+func (s *smtpTestServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// seen returns a copy of the sessions so far
+func (s *smtpTestServer) seen() []smtpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []smtpSession
+	for _, sess := range s.sessions {
+		sessions = append(sessions, *sess)
+	}
+	return sessions
+}
+
+func (s *smtpTestServer) serve(raw net.Conn) {
+	sess := &smtpSession{}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, raw)
+	s.sessions = append(s.sessions, sess)
+	s.mu.Unlock()
+
+	defer raw.Close()
+
+	var conn net.Conn = raw
+	if s.implicitTLS {
+		conn = tls.Server(raw, s.tlsConfig)
+		s.update(func() { sess.tls = true })
+	}
+	text := textproto.NewConn(conn)
+
+	_ = text.PrintfLine("220 localhost ESMTP test server")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost"}
+			s.mu.Lock()
+			if !sess.tls {
+				lines = append(lines, "STARTTLS")
+			}
+			s.mu.Unlock()
+			lines = append(lines, "AUTH PLAIN LOGIN CRAM-MD5", "8BITMIME")
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				_ = text.PrintfLine("250%s%s", sep, l)
+			}
+
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err = tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			s.update(func() { sess.tls = true })
+
+		case "AUTH":
+			mech, ok := s.authenticate(text, arg)
+			if !ok {
+				_ = text.PrintfLine("535 authentication failed")
+				continue
+			}
+			s.update(func() { sess.authMech = mech })
+			_ = text.PrintfLine("235 authenticated")
+
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			body, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.update(func() { sess.messages = append(sess.messages, strings.Join(body, "\n")) })
+			_ = text.PrintfLine("250 queued")
+
+		case "RCPT":
+			s.update(func() { sess.rcpts++ })
+			if strings.Contains(arg, testRejectedAddress) {
+				_ = text.PrintfLine("550 no such user")
+				continue
+			}
+			_ = text.PrintfLine("250 ok")
+
+		case "MAIL", "RSET", "NOOP":
+			_ = text.PrintfLine("250 ok")
+
+		case "QUIT":
+			s.update(func() { sess.quit = true })
+			_ = text.PrintfLine("221 bye")
+			return
+
+		default:
+			_ = text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// update changes a session under the server's lock
+func (s *smtpTestServer) update(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// authenticate runs an AUTH exchange, and returns the mechanism and whether the credentials were right
+func (s *smtpTestServer) authenticate(text *textproto.Conn, arg string) (string, bool) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return "", false
+	}
+	mech := strings.ToUpper(fields[0])
+
+	// challenge sends a 334 challenge and returns the decoded answer
+	challenge := func(msg string) string {
+		_ = text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(msg)))
+		line, err := text.ReadLine()
+		if err != nil {
+			return ""
+		}
+		answer, _ := base64.StdEncoding.DecodeString(line)
+		return string(answer)
+	}
+
+	switch mech {
+	case "PLAIN":
+		if len(fields) < 2 {
+			return mech, false
+		}
+		decoded, _ := base64.StdEncoding.DecodeString(fields[1])
+		parts := strings.Split(string(decoded), "\x00")
+		return mech, len(parts) == 3 && parts[1] == testSMTPUser && parts[2] == testSMTPPassword
+
+	case "LOGIN":
+		var user string
+		if len(fields) > 1 {
+			decoded, _ := base64.StdEncoding.DecodeString(fields[1])
+			user = string(decoded)
+		} else {
+			user = challenge("Username:")
+		}
+		password := challenge("Password:")
+		return mech, user == testSMTPUser && password == testSMTPPassword
+
+	case "CRAM-MD5":
+		nonce := "<1896.697170952@localhost>"
+		parts := strings.Fields(challenge(nonce))
+		if len(parts) != 2 {
+			return mech, false
+		}
+		d := hmac.New(md5.New, []byte(testSMTPPassword))
+		d.Write([]byte(nonce))
+		return mech, parts[0] == testSMTPUser && parts[1] == hex.EncodeToString(d.Sum(nil))
+	}
+
+	return mech, false
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 that no system pool trusts
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// testEmail returns a message with subject
+func testEmail(subject string) *mail.Email {
+	return NewEmail(channeldata.MailData{
+		FromAddress: "observer@example.com",
+		ToAddress:   "admin@example.com",
+		Subject:     subject,
+	}, "<p>"+subject+"</p>", subject)
+}
+
+func TestSMTPSettingsFromPreferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs map[string]string
+		want  SMTPSettings
+	}{
+		{
+			"local server defaults",
+			map[string]string{"smtp_server": "localhost", "smtp_port": "1025"},
+			SMTPSettings{Host: "localhost", Port: 1025, Encryption: EncryptionNone, Auth: AuthPlain},
+		},
+		{
+			"remote server defaults",
+			map[string]string{"smtp_server": "smtp.example.com", "smtp_port": "587", "smtp_user": "u", "smtp_password": "p"},
+			SMTPSettings{Host: "smtp.example.com", Port: 587, Username: "u", Password: "p", Encryption: EncryptionSTARTTLS, Auth: AuthLogin},
+		},
+		{
+			"explicit settings",
+			map[string]string{"smtp_server": "smtp.example.com", "smtp_port": "465", "smtp_encryption": "ssl",
+				"smtp_auth": "crammd5", "smtp_skip_verify": "1", "smtp_timeout": "3s"},
+			SMTPSettings{Host: "smtp.example.com", Port: 465, Encryption: EncryptionSSL, Auth: AuthCRAMMD5,
+				SkipVerify: true, Timeout: 3 * time.Second},
+		},
+		{
+			"bad timeout is ignored",
+			map[string]string{"smtp_server": "127.0.0.1", "smtp_port": "25", "smtp_timeout": "soon"},
+			SMTPSettings{Host: "127.0.0.1", Port: 25, Encryption: EncryptionNone, Auth: AuthPlain},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SMTPSettingsFromPreferences(tt.prefs); got != tt.want {
+				t.Errorf("settings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSMTPSettingsServer(t *testing.T) {
+	tests := []struct {
+		name           string
+		settings       SMTPSettings
+		wantEncryption mail.Encryption
+		wantAuth       mail.AuthType
+		wantTimeout    time.Duration
+		wantErr        bool
+	}{
+		{"none and plain", SMTPSettings{Encryption: EncryptionNone, Auth: AuthPlain}, mail.EncryptionNone, mail.AuthPlain, defaultTimeout, false},
+		{"ssl and login", SMTPSettings{Encryption: EncryptionSSL, Auth: AuthLogin}, mail.EncryptionSSLTLS, mail.AuthLogin, defaultTimeout, false},
+		{"starttls and cram-md5", SMTPSettings{Encryption: EncryptionSTARTTLS, Auth: AuthCRAMMD5, Timeout: time.Second}, mail.EncryptionSTARTTLS, mail.AuthCRAMMD5, time.Second, false},
+		{"unknown encryption", SMTPSettings{Encryption: "tls13", Auth: AuthPlain}, 0, 0, 0, true},
+		{"unknown auth", SMTPSettings{Encryption: EncryptionNone, Auth: "oauth"}, 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.Host = "smtp.example.com"
+			tt.settings.SkipVerify = true
+
+			server, err := tt.settings.Server(true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if server.Encryption != tt.wantEncryption {
+				t.Errorf("encryption = %s, want %s", server.Encryption, tt.wantEncryption)
+			}
+			if server.Authentication != tt.wantAuth {
+				t.Errorf("auth = %v, want %v", server.Authentication, tt.wantAuth)
+			}
+			if server.ConnectTimeout != tt.wantTimeout || server.SendTimeout != tt.wantTimeout {
+				t.Errorf("timeouts = %s and %s, want %s", server.ConnectTimeout, server.SendTimeout, tt.wantTimeout)
+			}
+			if !server.KeepAlive {
+				t.Error("keep alive is off")
+			}
+			if server.TLSConfig.ServerName != "smtp.example.com" || !server.TLSConfig.InsecureSkipVerify {
+				t.Errorf("tls config = %+v, want the host name and skip verify", server.TLSConfig)
+			}
+		})
+	}
+}
+
+func TestConnectionSend(t *testing.T) {
+	tests := []struct {
+		encryption string
+		auth       string
+		wantTLS    bool
+		wantMech   string
+	}{
+		{EncryptionNone, AuthPlain, false, "PLAIN"},
+		{EncryptionNone, AuthLogin, false, "LOGIN"},
+		{EncryptionNone, AuthCRAMMD5, false, "CRAM-MD5"},
+		{EncryptionSTARTTLS, AuthPlain, true, "PLAIN"},
+		{EncryptionSTARTTLS, AuthLogin, true, "LOGIN"},
+		{EncryptionSSL, AuthCRAMMD5, true, "CRAM-MD5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encryption+" "+tt.auth, func(t *testing.T) {
+			srv := newSMTPTestServer(t, tt.encryption == EncryptionSSL)
+
+			var c Connection
+			defer c.Close()
+
+			if err := c.Send(srv.settings(tt.encryption, tt.auth), testEmail("Web on host1 is problem")); err != nil {
+				t.Fatalf("send: %s", err)
+			}
+
+			sessions := srv.seen()
+			if len(sessions) != 1 {
+				t.Fatalf("%d connections, want 1", len(sessions))
+			}
+			if sessions[0].tls != tt.wantTLS {
+				t.Errorf("tls = %t, want %t", sessions[0].tls, tt.wantTLS)
+			}
+			if sessions[0].authMech != tt.wantMech {
+				t.Errorf("auth = %q, want %q", sessions[0].authMech, tt.wantMech)
+			}
+			if len(sessions[0].messages) != 1 || !strings.Contains(sessions[0].messages[0], "Subject: Web on host1 is problem") {
+				t.Errorf("messages = %q, want the one sent", sessions[0].messages)
+			}
+		})
+	}
+}
+
+func TestConnectionSendVerifiesCertificate(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+
+	settings := srv.settings(EncryptionSTARTTLS, AuthPlain)
+	settings.SkipVerify = false
+
+	var c Connection
+	defer c.Close()
+
+	if err := c.Send(settings, testEmail("untrusted")); err == nil {
+		t.Fatal("send succeeded with a self signed certificate and SkipVerify off")
+	}
+	if c.client != nil {
+		t.Error("connection is left open after a failed connect")
+	}
+}
+
+func TestConnectionSendWrongPassword(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+
+	settings := srv.settings(EncryptionNone, AuthPlain)
+	settings.Password = "wrong"
+
+	var c Connection
+	defer c.Close()
+
+	if err := c.Send(settings, testEmail("denied")); err == nil {
+		t.Fatal("send succeeded with the wrong password")
+	}
+	if c.client != nil {
+		t.Error("connection is left open after failed auth")
+	}
+}
+
+func TestConnectionReusesConnection(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+	settings := srv.settings(EncryptionNone, AuthPlain)
+
+	var c Connection
+	defer c.Close()
+
+	for i := 1; i <= 3; i++ {
+		if err := c.Send(settings, testEmail(fmt.Sprintf("message %d", i))); err != nil {
+			t.Fatalf("send %d: %s", i, err)
+		}
+	}
+
+	sessions := srv.seen()
+	if len(sessions) != 1 {
+		t.Fatalf("%d connections, want 1", len(sessions))
+	}
+	if len(sessions[0].messages) != 3 {
+		t.Errorf("%d messages on the connection, want 3", len(sessions[0].messages))
+	}
+}
+
+func TestConnectionReconnectsAfterServerDrop(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+	settings := srv.settings(EncryptionSTARTTLS, AuthLogin)
+
+	var c Connection
+	defer c.Close()
+
+	if err := c.Send(settings, testEmail("before")); err != nil {
+		t.Fatalf("first send: %s", err)
+	}
+
+	srv.drop()
+
+	if err := c.Send(settings, testEmail("after")); err != nil {
+		t.Fatalf("send after the server dropped the connection: %s", err)
+	}
+
+	sessions := srv.seen()
+	if len(sessions) != 2 {
+		t.Fatalf("%d connections, want 2", len(sessions))
+	}
+	if len(sessions[1].messages) != 1 || !strings.Contains(sessions[1].messages[0], "Subject: after") {
+		t.Errorf("messages on the new connection = %q, want the one sent after the drop", sessions[1].messages)
+	}
+	if !sessions[1].tls || sessions[1].authMech != "LOGIN" {
+		t.Errorf("new connection tls %t auth %q, want STARTTLS and LOGIN again", sessions[1].tls, sessions[1].authMech)
+	}
+}
+
+func TestConnectionDoesNotRetryRefusedMessage(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+	settings := srv.settings(EncryptionNone, AuthPlain)
+
+	var c Connection
+	defer c.Close()
+
+	if err := c.Send(settings, testEmail("accepted")); err != nil {
+		t.Fatalf("first send: %s", err)
+	}
+
+	refused := NewEmail(channeldata.MailData{
+		FromAddress: "observer@example.com",
+		ToAddress:   testRejectedAddress,
+		Subject:     "refused",
+	}, "<p>refused</p>", "refused")
+	if err := c.Send(settings, refused); err == nil {
+		t.Fatal("send to a refused recipient succeeded")
+	}
+
+	sessions := srv.seen()
+	if len(sessions) != 1 {
+		t.Fatalf("%d connections, want 1, without reconnecting to retry the refused message", len(sessions))
+	}
+	if sessions[0].rcpts != 2 {
+		t.Errorf("%d recipients sent, want 2, one per message", sessions[0].rcpts)
+	}
+}
+
+func TestConnectionReconnectsWhenSettingsChange(t *testing.T) {
+	srv := newSMTPTestServer(t, false)
+
+	var c Connection
+	defer c.Close()
+
+	if err := c.Send(srv.settings(EncryptionNone, AuthPlain), testEmail("plain")); err != nil {
+		t.Fatalf("first send: %s", err)
+	}
+
+	if err := c.Send(srv.settings(EncryptionNone, AuthCRAMMD5), testEmail("cram-md5")); err != nil {
+		t.Fatalf("send with new settings: %s", err)
+	}
+
+	sessions := srv.seen()
+	if len(sessions) != 2 {
+		t.Fatalf("%d connections, want 2", len(sessions))
+	}
+	if !sessions[0].quit {
+		t.Error("the old connection wasn't closed with QUIT")
+	}
+	if sessions[1].authMech != "CRAM-MD5" {
+		t.Errorf("new connection auth = %q, want CRAM-MD5", sessions[1].authMech)
+	}
+}
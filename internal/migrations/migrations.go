@@ -0,0 +1,272 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"server_monitor/internal/driver"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// files holds the migrations for each database type, named <version>_<name>.up.sql and <version>_<name>.down.sql.
+// Up migrations only create what is missing, so a database set up before migrations existed is adopted as is.
+//
+//go:embed mysql postgres sqlite
+var files embed.FS
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the migrations for one database and records them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	dbType     string
+	migrations []Migration
+}
+
+// New returns a migrator for db, which is a mysql, postgres or sqlite database
+func New(db *sql.DB, dbType string) (*Migrator, error) {
+	migrations, err := load(dbType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dbType:     dbType,
+		migrations: migrations,
+	}, nil
+}
+
+// load reads the migrations for dbType, oldest first
+func load(dbType string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dbType)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database type %q", dbType)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, e := range entries {
+		name := e.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("bad migration file name %s", name)
+		}
+
+		content, err := files.ReadFile(path.Join(dbType, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, parts[1])
+		}
+
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every migration that has not been applied yet, and returns them
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		if err = m.run(mig, mig.up, true); err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
+
+// Down reverts the most recently applied migration and returns it, or nil if none is applied
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+
+		if err = m.run(mig, mig.down, false); err != nil {
+			return nil, err
+		}
+		return &mig, nil
+	}
+
+	return nil, nil
+}
+
+// Status lists every migration, oldest first, with when it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+
+	return statuses, nil
+}
+
+// run executes the statements of one direction of mig and records the result, in a transaction.
+// MySQL commits schema changes as it goes, so there a failed migration may be left half done.
+func (m *Migrator) run(mig Migration, script string, up bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements(script) {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.Exec(driver.Rebind(m.dbType, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			mig.Version, mig.Name, time.Now().UTC())
+	} else {
+		_, err = tx.Exec(driver.Rebind(m.dbType, "DELETE FROM schema_migrations WHERE version = ?"), mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applied returns when each applied migration was applied, by version, creating the schema_migrations table if needed
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	timeType := "TIMESTAMP"
+	switch m.dbType {
+	case driver.Postgres:
+		timeType = "TIMESTAMPTZ"
+	case driver.SQLite:
+		// the sqlite driver only reads DATETIME columns as times
+		timeType = "DATETIME"
+	}
+
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at %s NOT NULL)`, timeType))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
+
+// statements splits a script into statements. Statements end with a semicolon at the end of a line,
+// and lines starting with -- are comments.
+func statements(script string) []string {
+	var stmts []string
+	var current []string
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(strings.Join(current, "\n")), ";"))
+			current = nil
+		}
+	}
+
+	if len(current) > 0 {
+		stmts = append(stmts, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+
+	return stmts
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS remember_tokens;
+DROP TABLE IF EXISTS users;
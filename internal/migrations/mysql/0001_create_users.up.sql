@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS users (
+    id           INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    email        VARCHAR(255) NOT NULL,
+    password     VARCHAR(60)  NOT NULL,
+    access_level INT          NOT NULL DEFAULT 1,
+    user_active  INT          NOT NULL DEFAULT 1,
+    created_at   DATETIME     NOT NULL,
+    updated_at   DATETIME     NOT NULL,
+    deleted_at   DATETIME     NULL,
+    INDEX users_email (email)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id        INT          NOT NULL,
+    remember_token VARCHAR(255) NOT NULL,
+    created_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX remember_tokens_token (remember_token),
+    CONSTRAINT remember_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS preferences (
+    id         INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    preference TEXT         NOT NULL,
+    created_at DATETIME     NOT NULL,
+    updated_at DATETIME     NOT NULL,
+    UNIQUE INDEX preferences_name (name)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
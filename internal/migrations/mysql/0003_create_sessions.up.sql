@@ -0,0 +1,7 @@
+-- the table scs mysqlstore expects
+CREATE TABLE IF NOT EXISTS sessions (
+    token  CHAR(43)     NOT NULL PRIMARY KEY,
+    data   BLOB         NOT NULL,
+    expiry TIMESTAMP(6) NOT NULL,
+    INDEX sessions_expiry_idx (expiry)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
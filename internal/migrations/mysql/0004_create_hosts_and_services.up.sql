@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS hosts (
+    id             INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    host_name      VARCHAR(255) NOT NULL,
+    canonical_name VARCHAR(255) NOT NULL DEFAULT '',
+    url            VARCHAR(255) NOT NULL DEFAULT '',
+    ip             VARCHAR(255) NOT NULL DEFAULT '',
+    ipv6           VARCHAR(255) NOT NULL DEFAULT '',
+    location       VARCHAR(255) NOT NULL DEFAULT '',
+    os             VARCHAR(255) NOT NULL DEFAULT '',
+    active         INT          NOT NULL DEFAULT 1,
+    created_at     DATETIME     NOT NULL,
+    updated_at     DATETIME     NOT NULL,
+    deleted_at     DATETIME     NULL
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS services (
+    id           INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    service_name VARCHAR(255) NOT NULL,
+    active       INT          NOT NULL DEFAULT 1,
+    icon         VARCHAR(255) NOT NULL DEFAULT '',
+    check_type   VARCHAR(255) NOT NULL DEFAULT '',
+    created_at   DATETIME     NOT NULL,
+    updated_at   DATETIME     NOT NULL,
+    deleted_at   DATETIME     NULL
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS host_services (
+    id              INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    host_id         INT          NOT NULL,
+    service_id      INT          NOT NULL,
+    active          INT          NOT NULL DEFAULT 1,
+    schedule_number INT          NOT NULL DEFAULT 3,
+    schedule_unit   VARCHAR(10)  NOT NULL DEFAULT 'm',
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    last_check      DATETIME     NULL,
+    last_message    TEXT         NOT NULL,
+    check_config    TEXT         NOT NULL,
+    cert_expiry     DATETIME     NULL,
+    cert_issuer     VARCHAR(255) NOT NULL DEFAULT '',
+    acknowledged_at DATETIME     NULL,
+    acknowledged_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at      DATETIME     NOT NULL,
+    updated_at      DATETIME     NOT NULL,
+    deleted_at      DATETIME     NULL,
+    INDEX host_services_status (status),
+    CONSTRAINT host_services_host_id FOREIGN KEY (host_id) REFERENCES hosts (id),
+    CONSTRAINT host_services_service_id FOREIGN KEY (service_id) REFERENCES services (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
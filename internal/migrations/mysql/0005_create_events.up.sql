@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS events (
+    id              INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    host_service_id INT          NOT NULL,
+    host_id         INT          NOT NULL,
+    service_id      INT          NOT NULL,
+    host_name       VARCHAR(255) NOT NULL DEFAULT '',
+    service_name    VARCHAR(255) NOT NULL DEFAULT '',
+    old_status      VARCHAR(20)  NOT NULL DEFAULT '',
+    new_status      VARCHAR(20)  NOT NULL DEFAULT '',
+    message         TEXT         NOT NULL,
+    duration_ms     BIGINT       NOT NULL DEFAULT 0,
+    created_at      DATETIME     NOT NULL,
+    INDEX events_created_at (created_at),
+    INDEX events_host_id (host_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS mail_queue (
+    id              INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    status          VARCHAR(20)  NOT NULL,
+    attempts        INT          NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL,
+    next_attempt_at DATETIME     NOT NULL,
+    sent_at         DATETIME     NULL,
+    to_address      VARCHAR(255) NOT NULL DEFAULT '',
+    subject         VARCHAR(255) NOT NULL DEFAULT '',
+    message         MEDIUMBLOB   NOT NULL,
+    created_at      DATETIME     NOT NULL,
+    updated_at      DATETIME     NOT NULL,
+    INDEX mail_queue_status (status, next_attempt_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS preference_audit;
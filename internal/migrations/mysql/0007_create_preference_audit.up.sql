@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS preference_audit (
+    id         INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    old_value  TEXT         NOT NULL,
+    new_value  TEXT         NOT NULL,
+    user_id    INT          NOT NULL DEFAULT 0,
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at DATETIME     NOT NULL,
+    INDEX preference_audit_changed_at (changed_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
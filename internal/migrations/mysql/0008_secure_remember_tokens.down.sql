@@ -0,0 +1,13 @@
+ALTER TABLE users DROP COLUMN signed_out_at;
+
+DROP TABLE IF EXISTS remember_tokens;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id        INT          NOT NULL,
+    remember_token VARCHAR(255) NOT NULL,
+    created_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX remember_tokens_token (remember_token),
+    CONSTRAINT remember_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
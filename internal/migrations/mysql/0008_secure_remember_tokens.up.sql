@@ -0,0 +1,16 @@
+-- tokens in the old format can't be checked against a hashed validator, so those users log in again
+DROP TABLE IF EXISTS remember_tokens;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id        INT         NOT NULL,
+    selector       VARCHAR(32) NOT NULL,
+    validator_hash VARCHAR(64) NOT NULL,
+    expires_at     DATETIME    NOT NULL,
+    created_at     DATETIME    NOT NULL,
+    last_used_at   DATETIME    NOT NULL,
+    UNIQUE INDEX remember_tokens_selector (selector),
+    CONSTRAINT remember_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+ALTER TABLE users ADD COLUMN signed_out_at DATETIME(6) NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS failed_logins (
+    id           INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    email        VARCHAR(255) NOT NULL,
+    user_id      INT          NOT NULL DEFAULT 0,
+    ip_address   VARCHAR(64)  NOT NULL,
+    attempted_at DATETIME     NOT NULL,
+    cleared      INT          NOT NULL DEFAULT 0,
+    INDEX failed_logins_email (email, attempted_at),
+    INDEX failed_logins_ip_address (ip_address, attempted_at),
+    INDEX failed_logins_user_id (user_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1 @@
+ALTER TABLE remember_tokens DROP COLUMN previous_validator_hash;
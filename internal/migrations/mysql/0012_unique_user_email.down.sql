@@ -0,0 +1,2 @@
+DROP INDEX users_email_unique ON users;
+ALTER TABLE users DROP COLUMN active_email;
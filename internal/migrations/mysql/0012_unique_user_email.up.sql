@@ -0,0 +1,5 @@
+-- emails are stored in lower case, and only one user that isn't deleted may have each one. MySQL has no
+-- partial indexes, so the unique index is on a column that is null for deleted users.
+UPDATE users SET email = LOWER(email);
+ALTER TABLE users ADD COLUMN active_email VARCHAR(255) AS (IF(deleted_at IS NULL, LOWER(email), NULL)) VIRTUAL;
+CREATE UNIQUE INDEX users_email_unique ON users (active_email);
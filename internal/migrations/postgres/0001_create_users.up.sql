@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    email        VARCHAR(255) NOT NULL,
+    password     BYTEA        NOT NULL,
+    access_level INTEGER      NOT NULL DEFAULT 1,
+    user_active  INTEGER      NOT NULL DEFAULT 1,
+    created_at   TIMESTAMPTZ  NOT NULL,
+    updated_at   TIMESTAMPTZ  NOT NULL,
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    remember_token VARCHAR(255) NOT NULL,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT current_timestamp,
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS remember_tokens_token ON remember_tokens (remember_token);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS host_services;
+DROP TABLE IF EXISTS services;
+DROP TABLE IF EXISTS hosts;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS hosts (
+    id             SERIAL PRIMARY KEY,
+    host_name      VARCHAR(255) NOT NULL,
+    canonical_name VARCHAR(255) NOT NULL DEFAULT '',
+    url            VARCHAR(255) NOT NULL DEFAULT '',
+    ip             VARCHAR(255) NOT NULL DEFAULT '',
+    ipv6           VARCHAR(255) NOT NULL DEFAULT '',
+    location       VARCHAR(255) NOT NULL DEFAULT '',
+    os             VARCHAR(255) NOT NULL DEFAULT '',
+    active         INTEGER      NOT NULL DEFAULT 1,
+    created_at     TIMESTAMPTZ  NOT NULL,
+    updated_at     TIMESTAMPTZ  NOT NULL,
+    deleted_at     TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS services (
+    id           SERIAL PRIMARY KEY,
+    service_name VARCHAR(255) NOT NULL,
+    active       INTEGER      NOT NULL DEFAULT 1,
+    icon         VARCHAR(255) NOT NULL DEFAULT '',
+    check_type   VARCHAR(255) NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ  NOT NULL,
+    updated_at   TIMESTAMPTZ  NOT NULL,
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS host_services (
+    id              SERIAL PRIMARY KEY,
+    host_id         INTEGER      NOT NULL REFERENCES hosts (id),
+    service_id      INTEGER      NOT NULL REFERENCES services (id),
+    active          INTEGER      NOT NULL DEFAULT 1,
+    schedule_number INTEGER      NOT NULL DEFAULT 3,
+    schedule_unit   VARCHAR(10)  NOT NULL DEFAULT 'm',
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    last_check      TIMESTAMPTZ,
+    last_message    TEXT         NOT NULL DEFAULT '',
+    check_config    TEXT         NOT NULL DEFAULT '{}',
+    cert_expiry     TIMESTAMPTZ,
+    cert_issuer     VARCHAR(255) NOT NULL DEFAULT '',
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ  NOT NULL,
+    updated_at      TIMESTAMPTZ  NOT NULL,
+    deleted_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS host_services_host_id ON host_services (host_id);
+CREATE INDEX IF NOT EXISTS host_services_status ON host_services (status);
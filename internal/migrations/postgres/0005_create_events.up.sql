@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS events (
+    id              SERIAL PRIMARY KEY,
+    host_service_id INTEGER      NOT NULL,
+    host_id         INTEGER      NOT NULL,
+    service_id      INTEGER      NOT NULL,
+    host_name       VARCHAR(255) NOT NULL DEFAULT '',
+    service_name    VARCHAR(255) NOT NULL DEFAULT '',
+    old_status      VARCHAR(20)  NOT NULL DEFAULT '',
+    new_status      VARCHAR(20)  NOT NULL DEFAULT '',
+    message         TEXT         NOT NULL DEFAULT '',
+    duration_ms     BIGINT       NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS events_created_at ON events (created_at);
+CREATE INDEX IF NOT EXISTS events_host_id ON events (host_id);
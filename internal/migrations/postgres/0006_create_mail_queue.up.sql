@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS mail_queue (
+    id              SERIAL PRIMARY KEY,
+    status          VARCHAR(20)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ  NOT NULL,
+    sent_at         TIMESTAMPTZ,
+    to_address      VARCHAR(255) NOT NULL DEFAULT '',
+    subject         VARCHAR(255) NOT NULL DEFAULT '',
+    message         BYTEA        NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL,
+    updated_at      TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mail_queue_status ON mail_queue (status, next_attempt_at);
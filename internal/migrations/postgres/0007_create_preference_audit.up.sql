@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS preference_audit (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    old_value  TEXT         NOT NULL DEFAULT '',
+    new_value  TEXT         NOT NULL DEFAULT '',
+    user_id    INTEGER      NOT NULL DEFAULT 0,
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS preference_audit_changed_at ON preference_audit (changed_at);
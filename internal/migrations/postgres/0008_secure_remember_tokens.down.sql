@@ -0,0 +1,13 @@
+ALTER TABLE users DROP COLUMN IF EXISTS signed_out_at;
+
+DROP TABLE IF EXISTS remember_tokens;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    remember_token VARCHAR(255) NOT NULL,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT current_timestamp,
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS remember_tokens_token ON remember_tokens (remember_token);
@@ -0,0 +1,17 @@
+-- tokens in the old format can't be checked against a hashed validator, so those users log in again
+DROP TABLE IF EXISTS remember_tokens;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    selector       VARCHAR(32) NOT NULL,
+    validator_hash VARCHAR(64) NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL,
+    last_used_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS remember_tokens_selector ON remember_tokens (selector);
+CREATE INDEX IF NOT EXISTS remember_tokens_user_id ON remember_tokens (user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS signed_out_at TIMESTAMPTZ;
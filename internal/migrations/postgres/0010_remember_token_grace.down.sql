@@ -0,0 +1 @@
+ALTER TABLE remember_tokens DROP COLUMN IF EXISTS previous_validator_hash;
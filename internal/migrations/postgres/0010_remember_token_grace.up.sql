@@ -0,0 +1,2 @@
+-- the validator a token had before its last rotation, accepted for a short while after it
+ALTER TABLE remember_tokens ADD COLUMN IF NOT EXISTS previous_validator_hash VARCHAR(64) NOT NULL DEFAULT '';
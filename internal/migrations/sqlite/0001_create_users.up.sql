@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    name         TEXT     NOT NULL DEFAULT '',
+    email        TEXT     NOT NULL,
+    password     BLOB     NOT NULL,
+    access_level INTEGER  NOT NULL DEFAULT 1,
+    user_active  INTEGER  NOT NULL DEFAULT 1,
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL,
+    deleted_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    remember_token TEXT    NOT NULL,
+    created_at     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS remember_tokens_token ON remember_tokens (remember_token);
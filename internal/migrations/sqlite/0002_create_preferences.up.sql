@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS preferences (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT     NOT NULL UNIQUE,
+    preference BLOB     NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,8 @@
+-- sessions for the scs session manager; expiry is a unix time in seconds
+CREATE TABLE IF NOT EXISTS sessions (
+    token  TEXT PRIMARY KEY,
+    data   BLOB    NOT NULL,
+    expiry INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry ON sessions (expiry);
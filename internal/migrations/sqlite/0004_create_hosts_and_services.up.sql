@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS hosts (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_name      TEXT     NOT NULL,
+    canonical_name TEXT     NOT NULL DEFAULT '',
+    url            TEXT     NOT NULL DEFAULT '',
+    ip             TEXT     NOT NULL DEFAULT '',
+    ipv6           TEXT     NOT NULL DEFAULT '',
+    location       TEXT     NOT NULL DEFAULT '',
+    os             TEXT     NOT NULL DEFAULT '',
+    active         INTEGER  NOT NULL DEFAULT 1,
+    created_at     DATETIME NOT NULL,
+    updated_at     DATETIME NOT NULL,
+    deleted_at     DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS services (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name TEXT     NOT NULL,
+    active       INTEGER  NOT NULL DEFAULT 1,
+    icon         TEXT     NOT NULL DEFAULT '',
+    check_type   TEXT     NOT NULL DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL,
+    deleted_at   DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS host_services (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_id         INTEGER  NOT NULL REFERENCES hosts (id),
+    service_id      INTEGER  NOT NULL REFERENCES services (id),
+    active          INTEGER  NOT NULL DEFAULT 1,
+    schedule_number INTEGER  NOT NULL DEFAULT 3,
+    schedule_unit   TEXT     NOT NULL DEFAULT 'm',
+    status          TEXT     NOT NULL DEFAULT 'pending',
+    last_check      DATETIME,
+    last_message    TEXT     NOT NULL DEFAULT '',
+    check_config    TEXT     NOT NULL DEFAULT '{}',
+    cert_expiry     DATETIME,
+    cert_issuer     TEXT     NOT NULL DEFAULT '',
+    acknowledged_at DATETIME,
+    acknowledged_by TEXT     NOT NULL DEFAULT '',
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL,
+    deleted_at      DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS host_services_host_id ON host_services (host_id);
+CREATE INDEX IF NOT EXISTS host_services_status ON host_services (status);
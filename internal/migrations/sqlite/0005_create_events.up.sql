@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS events (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_service_id INTEGER  NOT NULL,
+    host_id         INTEGER  NOT NULL,
+    service_id      INTEGER  NOT NULL,
+    host_name       TEXT     NOT NULL DEFAULT '',
+    service_name    TEXT     NOT NULL DEFAULT '',
+    old_status      TEXT     NOT NULL DEFAULT '',
+    new_status      TEXT     NOT NULL DEFAULT '',
+    message         TEXT     NOT NULL DEFAULT '',
+    duration_ms     INTEGER  NOT NULL DEFAULT 0,
+    created_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS events_created_at ON events (created_at);
+CREATE INDEX IF NOT EXISTS events_host_id ON events (host_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS mail_queue (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    status          TEXT     NOT NULL,
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    last_error      TEXT     NOT NULL DEFAULT '',
+    next_attempt_at DATETIME NOT NULL,
+    sent_at         DATETIME,
+    to_address      TEXT     NOT NULL DEFAULT '',
+    subject         TEXT     NOT NULL DEFAULT '',
+    message         BLOB     NOT NULL,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mail_queue_status ON mail_queue (status, next_attempt_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS preference_audit (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT     NOT NULL,
+    old_value  TEXT     NOT NULL DEFAULT '',
+    new_value  TEXT     NOT NULL DEFAULT '',
+    user_id    INTEGER  NOT NULL DEFAULT 0,
+    changed_by TEXT     NOT NULL DEFAULT '',
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS preference_audit_changed_at ON preference_audit (changed_at);
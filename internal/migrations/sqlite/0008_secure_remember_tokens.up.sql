@@ -0,0 +1,17 @@
+-- tokens in the old format can't be checked against a hashed validator, so those users log in again
+DROP TABLE IF EXISTS remember_tokens;
+
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    selector       TEXT     NOT NULL,
+    validator_hash TEXT     NOT NULL,
+    expires_at     DATETIME NOT NULL,
+    created_at     DATETIME NOT NULL,
+    last_used_at   DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS remember_tokens_selector ON remember_tokens (selector);
+CREATE INDEX IF NOT EXISTS remember_tokens_user_id ON remember_tokens (user_id);
+
+ALTER TABLE users ADD COLUMN signed_out_at DATETIME;
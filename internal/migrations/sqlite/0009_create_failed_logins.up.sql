@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS failed_logins (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    email        TEXT     NOT NULL,
+    user_id      INTEGER  NOT NULL DEFAULT 0,
+    ip_address   TEXT     NOT NULL,
+    attempted_at DATETIME NOT NULL,
+    cleared      INTEGER  NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS failed_logins_email ON failed_logins (email, attempted_at);
+CREATE INDEX IF NOT EXISTS failed_logins_ip_address ON failed_logins (ip_address, attempted_at);
+CREATE INDEX IF NOT EXISTS failed_logins_user_id ON failed_logins (user_id);
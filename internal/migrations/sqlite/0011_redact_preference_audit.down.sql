@@ -0,0 +1 @@
+-- the redacted values can't be restored
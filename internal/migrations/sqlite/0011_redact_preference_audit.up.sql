@@ -0,0 +1,5 @@
+-- audit rows written before secret preferences were redacted hold their values
+UPDATE preference_audit
+SET old_value = CASE WHEN old_value = '' THEN '' ELSE 'changed' END,
+    new_value = CASE WHEN new_value = '' THEN '' ELSE 'changed' END
+WHERE name IN ('smtp_password', 'twilio_auth_token', 'slack_webhook_url', 'teams_webhook_url', 'webhook_url');
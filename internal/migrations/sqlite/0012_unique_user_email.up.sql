@@ -0,0 +1,3 @@
+-- emails are stored in lower case, and only one user that isn't deleted may have each one
+UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_unique ON users (LOWER(email)) WHERE deleted_at IS NULL;
@@ -2,6 +2,8 @@ package models
 
 import (
 	"errors"
+	"github.com/robfig/cron/v3"
+	"server_monitor/internal/channeldata"
 	"time"
 )
 
@@ -27,9 +29,73 @@ type User struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   time.Time
+	// SignedOutAt is when the user was last signed out of every device, sessions from before then are ended
+	SignedOutAt time.Time
 	Preferences map[string]string
 }
 
+// FailedLogin is a login attempt with a wrong password, or for an email that has no account
+type FailedLogin struct {
+	ID          int
+	Email       string
+	UserID      int
+	IPAddress   string
+	AttemptedAt time.Time
+	// Cleared is set when the account is logged in to successfully afterwards
+	Cleared bool
+}
+
+// LoginFailures is how many failed logins there have been recently, and when the last one was
+type LoginFailures struct {
+	Count int
+	Last  time.Time
+}
+
+// RememberToken is a remember me token. The cookie holds the selector and a validator; only a hash of
+// the validator is stored, so the table can't be used to log in.
+type RememberToken struct {
+	ID            int
+	UserID        int
+	Selector      string
+	ValidatorHash string
+	// PreviousValidatorHash is the validator hash before the last rotation
+	PreviousValidatorHash string
+	ExpiresAt             time.Time
+	CreatedAt             time.Time
+	LastUsedAt            time.Time
+}
+
+// Access levels for User.AccessLevel; each level can do everything the levels below it can
+const (
+	// AccessViewer can see the dashboards, hosts, events and schedule
+	AccessViewer = 1
+	// AccessOperator can also acknowledge problems and pause checks
+	AccessOperator = 2
+	// AccessAdmin can also manage users, settings, hosts and mail
+	AccessAdmin = 3
+)
+
+// AccessLevels lists the access levels, lowest first
+var AccessLevels = []int{AccessViewer, AccessOperator, AccessAdmin}
+
+// AccessLevelName returns the role name of an access level
+func AccessLevelName(level int) string {
+	switch level {
+	case AccessViewer:
+		return "viewer"
+	case AccessOperator:
+		return "operator"
+	case AccessAdmin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+// HasAccess returns true if the user's access level is at least level
+func (u User) HasAccess(level int) bool {
+	return u.AccessLevel >= level
+}
+
 // Preference model
 type Preference struct {
 	ID         int
@@ -38,3 +104,122 @@ type Preference struct {
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
+
+// PreferenceChange is a record in the preference_audit table
+type PreferenceChange struct {
+	ID        int
+	Name      string
+	OldValue  string
+	NewValue  string
+	UserID    int
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+// Host model
+type Host struct {
+	ID            int
+	HostName      string
+	CanonicalName string
+	URL           string
+	IP            string
+	IPV6          string
+	Location      string
+	OS            string
+	Active        int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	HostServices  []HostService
+}
+
+// Service model
+type Service struct {
+	ID          int
+	ServiceName string
+	Active      int
+	Icon        string
+	CheckType   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// HostService model
+type HostService struct {
+	ID             int
+	HostID         int
+	ServiceID      int
+	Active         int
+	ScheduleNumber int
+	ScheduleUnit   string
+	Status         string
+	LastCheck      time.Time
+	LastMessage    string
+	CheckConfig    map[string]string
+	CertExpiry     time.Time
+	CertIssuer     string
+	AcknowledgedAt time.Time
+	AcknowledgedBy string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Service        Service
+	HostName       string
+}
+
+// Schedule model
+type Schedule struct {
+	ID            int
+	EntryID       cron.EntryID
+	Entry         cron.Entry
+	Host          string
+	Service       string
+	LastRunFromHS time.Time
+	HostServiceID int
+	ScheduleText  string
+}
+
+// Event model
+type Event struct {
+	ID            int
+	HostServiceID int
+	HostID        int
+	ServiceID     int
+	HostName      string
+	ServiceName   string
+	OldStatus     string
+	NewStatus     string
+	Message       string
+	Duration      time.Duration
+	CreatedAt     time.Time
+}
+
+// EventFilter holds the criteria used to list events
+type EventFilter struct {
+	HostID    int
+	ServiceID int
+	Status    string
+	From      time.Time
+	To        time.Time
+	Page      int
+	PerPage   int
+}
+
+// Mail queue statuses
+const (
+	MailQueued  = "queued"
+	MailSending = "sending"
+	MailSent    = "sent"
+	MailFailed  = "failed"
+)
+
+// QueuedMail is a mail job stored in the mail_queue table
+type QueuedMail struct {
+	ID            int
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        time.Time
+	Message       channeldata.MailData
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,153 @@
+package notifiers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"server_monitor/internal/checks"
+	"sort"
+	"time"
+)
+
+// Alert is a change in a host service's status that someone should hear about
+type Alert struct {
+	Identifier  string
+	HostID      int
+	HostName    string
+	ServiceName string
+	OldStatus   string
+	NewStatus   string
+	Message     string
+	CheckedAt   time.Time
+}
+
+// Title is a one line summary of the alert
+func (a Alert) Title() string {
+	return fmt.Sprintf("%s on %s is %s", a.ServiceName, a.HostName, a.NewStatus)
+}
+
+// Notifier delivers alerts over one channel
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// Provider describes a kind of notifier and how to build it from preferences
+type Provider struct {
+	// Name identifies the provider; it is switched on by the notify_via_<Name> preference
+	Name  string
+	Label string
+	// Keys are the preferences, besides notify_via_<Name>, that configure the provider
+	Keys []string
+	// Secrets are the Keys that hold credentials; their values are kept out of the preference audit log
+	Secrets []string
+	// Requires are other preferences that must also be "1" for the provider to be on
+	Requires []string
+	// New builds a notifier from the preference map
+	New func(prefs map[string]string) (Notifier, error)
+}
+
+// EnabledKey is the preference that switches the provider on
+func (p Provider) EnabledKey() string {
+	return "notify_via_" + p.Name
+}
+
+// Enabled returns true if the preferences switch the provider on
+func (p Provider) Enabled(prefs map[string]string) bool {
+	if prefs[p.EnabledKey()] != "1" {
+		return false
+	}
+
+	for _, key := range p.Requires {
+		if prefs[key] != "1" {
+			return false
+		}
+	}
+
+	return true
+}
+
+var registry = make(map[string]Provider)
+
+// Register adds a provider to the registry, replacing any with the same name
+func Register(p Provider) {
+	registry[p.Name] = p
+}
+
+// Providers returns every registered provider, sorted by name
+func Providers() []Provider {
+	var providers []Provider
+	for _, p := range registry {
+		providers = append(providers, p)
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+
+	return providers
+}
+
+func init() {
+	Register(Provider{
+		Name:    "slack",
+		Label:   "Slack",
+		Keys:    []string{"slack_webhook_url"},
+		Secrets: []string{"slack_webhook_url"},
+		New:     newSlack,
+	})
+	Register(Provider{
+		Name:     "sms",
+		Label:    "Text Message",
+		Keys:     []string{"sms_provider", "sms_notify_number", "twilio_phone_number", "twilio_sid", "twilio_auth_token", "twilio_base_url"},
+		Secrets:  []string{"twilio_auth_token"},
+		Requires: []string{"sms_enabled"},
+		New:      newSMS,
+	})
+	Register(Provider{
+		Name:    "teams",
+		Label:   "Microsoft Teams",
+		Keys:    []string{"teams_webhook_url"},
+		Secrets: []string{"teams_webhook_url"},
+		New:     newTeams,
+	})
+	Register(Provider{
+		Name:    "webhook",
+		Label:   "Webhook",
+		Keys:    []string{"webhook_url", "webhook_template"},
+		Secrets: []string{"webhook_url"},
+		New:     newWebhook,
+	})
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts body to url, and returns an error unless the response is a 2xx
+func postJSON(url string, body []byte) error {
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+
+	return nil
+}
+
+// statusColor is the hex colour used for a status in chat messages
+func statusColor(status string) string {
+	switch status {
+	case checks.StatusHealthy:
+		return "2EB67D"
+	case checks.StatusWarning:
+		return "ECB22E"
+	case checks.StatusProblem:
+		return "E01E5A"
+	}
+	return "6C757D"
+}
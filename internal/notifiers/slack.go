@@ -0,0 +1,44 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Slack posts alerts to a Slack incoming webhook
+type Slack struct {
+	WebhookURL string
+}
+
+func newSlack(prefs map[string]string) (Notifier, error) {
+	if prefs["slack_webhook_url"] == "" {
+		return nil, errors.New("no webhook url")
+	}
+	return Slack{WebhookURL: prefs["slack_webhook_url"]}, nil
+}
+
+// Notify posts the alert as a message with a coloured attachment
+func (s Slack) Notify(a Alert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"text": a.Title(),
+		"attachments": []map[string]interface{}{
+			{
+				"color":  "#" + statusColor(a.NewStatus),
+				"text":   a.Message,
+				"footer": a.Identifier,
+				"ts":     a.CheckedAt.Unix(),
+				"fields": []map[string]interface{}{
+					{"title": "Host", "value": a.HostName, "short": true},
+					{"title": "Service", "value": a.ServiceName, "short": true},
+					{"title": "Was", "value": a.OldStatus, "short": true},
+					{"title": "Now", "value": a.NewStatus, "short": true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJSON(s.WebhookURL, body)
+}
@@ -0,0 +1,58 @@
+package notifiers
+
+import (
+	"fmt"
+	"server_monitor/internal/checks"
+	"server_monitor/internal/sms"
+)
+
+// maxSMSMessageLength is how much of the check output goes into a text message
+const maxSMSMessageLength = 100
+
+// SMSSender is a text message provider
+type SMSSender interface {
+	Send(to, body string) error
+}
+
+// SMS texts problems, and recoveries from them, to a phone number
+type SMS struct {
+	Sender SMSSender
+	To     string
+}
+
+func newSMS(prefs map[string]string) (Notifier, error) {
+	switch prefs["sms_provider"] {
+	case "twilio":
+		return SMS{
+			Sender: sms.Twilio{
+				BaseURL:    prefs["twilio_base_url"],
+				AccountSID: prefs["twilio_sid"],
+				AuthToken:  prefs["twilio_auth_token"],
+				From:       prefs["twilio_phone_number"],
+			},
+			To: prefs["sms_notify_number"],
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown sms provider %q", prefs["sms_provider"])
+}
+
+// Notify texts the alert if it is a problem, or a recovery from one
+func (s SMS) Notify(a Alert) error {
+	var label string
+	switch {
+	case a.NewStatus == checks.StatusProblem:
+		label = "PROBLEM"
+	case a.NewStatus == checks.StatusHealthy && a.OldStatus == checks.StatusProblem:
+		label = "RECOVERY"
+	default:
+		return nil
+	}
+
+	message := []rune(a.Message)
+	if len(message) > maxSMSMessageLength {
+		message = append(message[:maxSMSMessageLength], []rune("...")...)
+	}
+
+	return s.Sender.Send(s.To, fmt.Sprintf("%s: %s - %s", label, a.Title(), string(message)))
+}
@@ -0,0 +1,47 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Teams posts alerts to a Microsoft Teams incoming webhook connector
+type Teams struct {
+	WebhookURL string
+}
+
+func newTeams(prefs map[string]string) (Notifier, error) {
+	if prefs["teams_webhook_url"] == "" {
+		return nil, errors.New("no webhook url")
+	}
+	return Teams{WebhookURL: prefs["teams_webhook_url"]}, nil
+}
+
+// Notify posts the alert as a connector message card
+func (t Teams) Notify(a Alert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    a.Title(),
+		"themeColor": statusColor(a.NewStatus),
+		"sections": []map[string]interface{}{
+			{
+				"activityTitle":    a.Title(),
+				"activitySubtitle": a.Identifier,
+				"text":             a.Message,
+				"facts": []map[string]string{
+					{"name": "Host", "value": a.HostName},
+					{"name": "Service", "value": a.ServiceName},
+					{"name": "Was", "value": a.OldStatus},
+					{"name": "Now", "value": a.NewStatus},
+					{"name": "Checked", "value": a.CheckedAt.Format("2006-01-02 15:04:05")},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJSON(t.WebhookURL, body)
+}
@@ -0,0 +1,75 @@
+package notifiers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+// DefaultWebhookTemplate is the body posted when no template is configured
+const DefaultWebhookTemplate = `{
+  "identifier": {{json .Identifier}},
+  "host": {{json .HostName}},
+  "service": {{json .ServiceName}},
+  "old_status": {{json .OldStatus}},
+  "new_status": {{json .NewStatus}},
+  "message": {{json .Message}},
+  "checked_at": {{json .CheckedAt}}
+}`
+
+// webhookFuncs are the functions available to a webhook template
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Webhook posts alerts as JSON built from a user defined Go template
+type Webhook struct {
+	URL      string
+	Template *template.Template
+}
+
+func newWebhook(prefs map[string]string) (Notifier, error) {
+	if prefs["webhook_url"] == "" {
+		return nil, errors.New("no webhook url")
+	}
+
+	tmpl, err := ParseWebhookTemplate(prefs["webhook_template"])
+	if err != nil {
+		return nil, err
+	}
+
+	return Webhook{URL: prefs["webhook_url"], Template: tmpl}, nil
+}
+
+// ParseWebhookTemplate parses a webhook body template; an empty one uses DefaultWebhookTemplate
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultWebhookTemplate
+	}
+
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// Notify renders the template for the alert and posts it
+func (wh Webhook) Notify(a Alert) error {
+	var body bytes.Buffer
+	if err := wh.Template.Execute(&body, a); err != nil {
+		return fmt.Errorf("could not render template: %w", err)
+	}
+
+	if !json.Valid(body.Bytes()) {
+		return errors.New("template did not render valid json")
+	}
+
+	return postJSON(wh.URL, body.Bytes())
+}
@@ -0,0 +1,105 @@
+package preferences
+
+import "server_monitor/internal/notifiers"
+
+// Kind is the type of a preference value
+type Kind int
+
+// Preference kinds. Bools are stored as "1" or "0", durations as strings like "10s".
+const (
+	String Kind = iota
+	Bool
+	Int
+	Duration
+)
+
+// Definition describes a preference that can be saved
+type Definition struct {
+	Name    string
+	Kind    Kind
+	Default string
+	// Min and Max bound an Int preference when Max is above zero
+	Min, Max int
+	// Choices, if set, are the only values allowed
+	Choices []string
+	// Check, if set, validates the value further
+	Check func(value string) error
+	// Secret preferences hold credentials, so the audit log records that they changed but not their values
+	Secret bool
+}
+
+// SecretMarker stands in for the value of a Secret preference in the audit log
+const SecretMarker = "changed"
+
+// AuditValue returns how value is recorded in the audit log for the named preference: the value itself,
+// or SecretMarker for a Secret preference that isn't empty
+func AuditValue(name, value string) string {
+	if d, ok := Lookup(name); ok && d.Secret && value != "" {
+		return SecretMarker
+	}
+
+	return value
+}
+
+var definitions = make(map[string]Definition)
+
+// Define adds or replaces a preference definition
+func Define(d Definition) {
+	definitions[d.Name] = d
+}
+
+// Lookup returns the definition of a preference
+func Lookup(name string) (Definition, bool) {
+	d, ok := definitions[name]
+	return d, ok
+}
+
+// Defaults returns the default value of every defined preference, by name
+func Defaults() map[string]string {
+	defaults := make(map[string]string, len(definitions))
+	for name, d := range definitions {
+		defaults[name] = d.Default
+	}
+
+	return defaults
+}
+
+func init() {
+	for _, d := range []Definition{
+		{Name: "monitoring_live", Kind: Bool, Default: "0"},
+		{Name: "site_url", Default: "http://localhost:4000"},
+		{Name: "notify_name"},
+		{Name: "notify_email"},
+		{Name: "notify_via_email", Kind: Bool, Default: "0"},
+		{Name: "smtp_server", Default: "localhost"},
+		{Name: "smtp_port", Kind: Int, Default: "1025", Min: 1, Max: 65535},
+		{Name: "smtp_user"},
+		{Name: "smtp_password", Secret: true},
+		{Name: "smtp_encryption", Choices: []string{"", "none", "ssl", "starttls"}},
+		{Name: "smtp_auth", Choices: []string{"", "plain", "login", "crammd5"}},
+		{Name: "smtp_skip_verify", Kind: Bool, Default: "0"},
+		{Name: "smtp_timeout", Kind: Duration, Default: "10s"},
+		{Name: "smtp_from_email"},
+		{Name: "smtp_from_name", Default: "Observer"},
+		{Name: "sms_enabled", Kind: Bool, Default: "0"},
+	} {
+		Define(d)
+	}
+
+	for _, p := range notifiers.Providers() {
+		Define(Definition{Name: p.EnabledKey(), Kind: Bool, Default: "0"})
+		secrets := make(map[string]bool, len(p.Secrets))
+		for _, key := range p.Secrets {
+			secrets[key] = true
+		}
+		for _, key := range p.Keys {
+			Define(Definition{Name: key, Secret: secrets[key]})
+		}
+	}
+
+	Define(Definition{Name: "sms_provider", Default: "twilio", Choices: []string{"", "twilio"}})
+	Define(Definition{Name: "webhook_template", Check: func(value string) error {
+		_, err := notifiers.ParseWebhookTemplate(value)
+		return err
+	}})
+}
@@ -0,0 +1,326 @@
+package preferences
+
+import (
+	"fmt"
+	"log"
+	"server_monitor/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store loads and saves preferences
+type Store interface {
+	AllPreferences() ([]models.Preference, error)
+	InsertOrUpdateSitePreferences(pm map[string]string, user models.User) error
+}
+
+// System is recorded as the user for changes the application makes by itself
+var System = models.User{Name: "system"}
+
+// InvalidError reports a preference value that failed validation
+type InvalidError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Reason)
+}
+
+// Change describes a preference that was given a new value
+type Change struct {
+	Name string
+	Old  string
+	New  string
+	// User is who made the change; values set with SetLocal have no user
+	User models.User
+}
+
+// subscriberBuffer is how many changes a subscriber can fall behind before it misses some
+const subscriberBuffer = 32
+
+type subscriber struct {
+	ch    chan Change
+	names map[string]bool
+}
+
+// Service reads and writes preferences. It keeps the current values in memory and is safe for
+// concurrent use; changes are saved through the store and sent to subscribers.
+type Service struct {
+	store Store
+	// saving serializes saves, so the values in memory are changed in the same order as the database
+	saving sync.Mutex
+
+	mu          sync.RWMutex
+	values      map[string]string
+	subscribers map[int]*subscriber
+	nextID      int
+}
+
+// NewService returns a service that saves preferences through store
+func NewService(store Store) *Service {
+	return &Service{
+		store:       store,
+		values:      make(map[string]string),
+		subscribers: make(map[int]*subscriber),
+	}
+}
+
+// Load reads the saved preferences. Defined preferences that were never saved get their defaults.
+func (s *Service) Load() error {
+	saved, err := s.store.AllPreferences()
+	if err != nil {
+		return err
+	}
+
+	values := Defaults()
+	for _, p := range saved {
+		values[p.Name] = string(p.Preference)
+	}
+
+	s.mu.Lock()
+	for name, value := range values {
+		s.values[name] = value
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Snapshot returns a copy of every current value, by name. Later changes don't affect it.
+func (s *Service) Snapshot() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]string, len(s.values))
+	for name, value := range s.values {
+		snapshot[name] = value
+	}
+
+	return snapshot
+}
+
+// Subscribe returns a channel that receives changes to the named preferences, or to every
+// preference if no names are given. The channel is buffered; a subscriber that falls further
+// behind misses changes. Call the returned function to unsubscribe, which closes the channel.
+func (s *Service) Subscribe(names ...string) (<-chan Change, func()) {
+	sub := &subscriber{ch: make(chan Change, subscriberBuffer)}
+	if len(names) > 0 {
+		sub.names = make(map[string]bool, len(names))
+		for _, name := range names {
+			sub.names[name] = true
+		}
+	}
+
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = sub
+	s.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers, id)
+			close(sub.ch)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// SetLocal sets a value in memory only, for settings that come from the command line rather than the database
+func (s *Service) SetLocal(name, value string) {
+	s.apply(map[string]string{name: value}, models.User{})
+}
+
+// Get returns the value of a preference, or its default if it has none
+func (s *Service) Get(name string) string {
+	s.mu.RLock()
+	value, ok := s.values[name]
+	s.mu.RUnlock()
+
+	if ok {
+		return value
+	}
+
+	d, _ := Lookup(name)
+	return d.Default
+}
+
+// GetBool returns a Bool preference
+func (s *Service) GetBool(name string) bool {
+	return s.Get(name) == "1"
+}
+
+// GetInt returns an Int preference, or its default if the saved value isn't a number
+func (s *Service) GetInt(name string) int {
+	n, err := strconv.Atoi(s.Get(name))
+	if err != nil {
+		d, _ := Lookup(name)
+		n, _ = strconv.Atoi(d.Default)
+	}
+
+	return n
+}
+
+// GetDuration returns a Duration preference, or its default if the saved value isn't a duration
+func (s *Service) GetDuration(name string) time.Duration {
+	v, err := time.ParseDuration(s.Get(name))
+	if err != nil {
+		d, _ := Lookup(name)
+		v, _ = time.ParseDuration(d.Default)
+	}
+
+	return v
+}
+
+// Set validates and saves a preference, recording user as the one who changed it
+func (s *Service) Set(name, value string, user models.User) error {
+	return s.SetMany(map[string]string{name: value}, user)
+}
+
+// SetBool saves a Bool preference
+func (s *Service) SetBool(name string, value bool, user models.User) error {
+	v := "0"
+	if value {
+		v = "1"
+	}
+
+	return s.Set(name, v, user)
+}
+
+// SetInt saves an Int preference
+func (s *Service) SetInt(name string, value int, user models.User) error {
+	return s.Set(name, strconv.Itoa(value), user)
+}
+
+// SetDuration saves a Duration preference
+func (s *Service) SetDuration(name string, value time.Duration, user models.User) error {
+	return s.Set(name, value.String(), user)
+}
+
+// SetMany validates values and saves them together. If any value is invalid nothing is saved,
+// and the error is an *InvalidError for the first one by name.
+func (s *Service) SetMany(values map[string]string, user models.User) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	normalized := make(map[string]string, len(values))
+	for _, name := range names {
+		v, err := Normalize(name, values[name])
+		if err != nil {
+			return err
+		}
+		normalized[name] = v
+	}
+
+	s.saving.Lock()
+	defer s.saving.Unlock()
+
+	if err := s.store.InsertOrUpdateSitePreferences(normalized, user); err != nil {
+		return err
+	}
+
+	s.apply(normalized, user)
+
+	return nil
+}
+
+// apply sets values in memory and tells subscribers about the ones that changed
+func (s *Service) apply(values map[string]string, user models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []Change
+	for name, value := range values {
+		old, ok := s.values[name]
+		s.values[name] = value
+		if !ok || old != value {
+			changes = append(changes, Change{Name: name, Old: old, New: value, User: user})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	for _, c := range changes {
+		for _, sub := range s.subscribers {
+			if sub.names != nil && !sub.names[c.Name] {
+				continue
+			}
+
+			select {
+			case sub.ch <- c:
+			default:
+				log.Printf("Preference subscriber is not keeping up, dropped change to %s", c.Name)
+			}
+		}
+	}
+}
+
+// Normalize validates value for the named preference and returns it the way it is stored
+func Normalize(name, value string) (string, error) {
+	d, ok := Lookup(name)
+	if !ok {
+		return "", &InvalidError{Name: name, Reason: "unknown preference"}
+	}
+
+	switch d.Kind {
+	case Bool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "", "0", "false", "off":
+			value = "0"
+		case "1", "true", "on":
+			value = "1"
+		default:
+			return "", &InvalidError{Name: name, Reason: "must be on or off"}
+		}
+
+	case Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return "", &InvalidError{Name: name, Reason: "must be a whole number"}
+		}
+		if d.Max > 0 && (n < d.Min || n > d.Max) {
+			return "", &InvalidError{Name: name, Reason: fmt.Sprintf("must be from %d to %d", d.Min, d.Max)}
+		}
+		value = strconv.Itoa(n)
+
+	case Duration:
+		v, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || v < 0 {
+			return "", &InvalidError{Name: name, Reason: "must be a duration like 30s or 5m"}
+		}
+		value = v.String()
+	}
+
+	if len(d.Choices) > 0 {
+		allowed := false
+		var listed []string
+		for _, c := range d.Choices {
+			allowed = allowed || value == c
+			if c != "" {
+				listed = append(listed, c)
+			}
+		}
+		if !allowed {
+			return "", &InvalidError{Name: name, Reason: fmt.Sprintf("must be one of %s", strings.Join(listed, ", "))}
+		}
+	}
+
+	if d.Check != nil {
+		if err := d.Check(value); err != nil {
+			return "", &InvalidError{Name: name, Reason: err.Error()}
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,108 @@
+package remember
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"server_monitor/internal/models"
+	"strings"
+	"time"
+)
+
+// Lifetime is how long a remember me token lasts without being used. Each use starts it again.
+const Lifetime = 30 * 24 * time.Hour
+
+// RotationGrace is how long after a rotation the previous validator still works, for requests
+// that were sent with the old cookie at the same time as the one that rotated it
+const RotationGrace = 10 * time.Second
+
+const (
+	selectorBytes  = 12
+	validatorBytes = 32
+)
+
+// CookieName returns the name of the remember me cookie for an app identifier
+func CookieName(identifier string) string {
+	return fmt.Sprintf("_%s_gowatcher_remember", identifier)
+}
+
+// New returns a token for a user and the value for its cookie
+func New(userID int) (models.RememberToken, string, error) {
+	selector, err := randomString(selectorBytes)
+	if err != nil {
+		return models.RememberToken{}, "", err
+	}
+
+	now := time.Now()
+	t := models.RememberToken{
+		UserID:    userID,
+		Selector:  selector,
+		CreatedAt: now,
+	}
+
+	return Rotate(t, now)
+}
+
+// Rotate gives a token a new validator and expiry, and returns it with the value for its cookie.
+// The old cookie value stops working once RotationGrace has passed.
+func Rotate(t models.RememberToken, now time.Time) (models.RememberToken, string, error) {
+	validator, err := randomString(validatorBytes)
+	if err != nil {
+		return t, "", err
+	}
+
+	t.PreviousValidatorHash = t.ValidatorHash
+	t.ValidatorHash = hash(validator)
+	t.ExpiresAt = now.Add(Lifetime)
+	t.LastUsedAt = now
+
+	return t, t.Selector + ":" + validator, nil
+}
+
+// Parse splits a cookie value into its selector and validator
+func Parse(value string) (selector, validator string, ok bool) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// Valid reports whether validator belongs to the token and the token hasn't expired
+func Valid(t models.RememberToken, validator string, now time.Time) bool {
+	if !now.Before(t.ExpiresAt) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash(validator)), []byte(t.ValidatorHash)) == 1
+}
+
+// ValidPrevious reports whether validator is the one the token had before it was rotated,
+// within RotationGrace of the rotation, and the token hasn't expired
+func ValidPrevious(t models.RememberToken, validator string, now time.Time) bool {
+	if t.PreviousValidatorHash == "" || !now.Before(t.ExpiresAt) || now.Sub(t.LastUsedAt) > RotationGrace {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash(validator)), []byte(t.PreviousValidatorHash)) == 1
+}
+
+// hash returns the hex SHA-256 of a validator, as it is stored
+func hash(validator string) string {
+	sum := sha256.Sum256([]byte(validator))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n bytes from crypto/rand, encoded for a cookie
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
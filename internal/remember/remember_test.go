@@ -0,0 +1,161 @@
+package remember
+
+import (
+	"server_monitor/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		wantSelector  string
+		wantValidator string
+		wantOK        bool
+	}{
+		{"selector and validator", "abc:def", "abc", "def", true},
+		{"validator with a colon", "abc:de:f", "abc", "de:f", true},
+		{"no colon", "abcdef", "", "", false},
+		{"no selector", ":def", "", "", false},
+		{"no validator", "abc:", "", "", false},
+		{"empty", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, validator, ok := Parse(tt.value)
+			if selector != tt.wantSelector || validator != tt.wantValidator || ok != tt.wantOK {
+				t.Errorf("Parse(%q) = %q, %q, %t, want %q, %q, %t",
+					tt.value, selector, validator, ok, tt.wantSelector, tt.wantValidator, tt.wantOK)
+			}
+		})
+	}
+}
+
+// newToken returns a token and its cookie's validator, and the validator it had before one rotation
+func newToken(t *testing.T, rotatedAt time.Time) (models.RememberToken, string, string) {
+	t.Helper()
+
+	token, first, err := New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, second, err := Rotate(token, rotatedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, previous, _ := Parse(first)
+	_, current, _ := Parse(second)
+
+	return token, current, previous
+}
+
+func TestValid(t *testing.T) {
+	rotatedAt := time.Now()
+	token, current, previous := newToken(t, rotatedAt)
+
+	tests := []struct {
+		name      string
+		validator string
+		now       time.Time
+		want      bool
+	}{
+		{"current validator", current, rotatedAt.Add(time.Hour), true},
+		{"previous validator", previous, rotatedAt, false},
+		{"wrong validator", current + "x", rotatedAt, false},
+		{"empty validator", "", rotatedAt, false},
+		{"just before expiry", current, token.ExpiresAt.Add(-time.Second), true},
+		{"at expiry", current, token.ExpiresAt, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(token, tt.validator, tt.now); got != tt.want {
+				t.Errorf("Valid = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidPrevious(t *testing.T) {
+	rotatedAt := time.Now()
+	token, current, previous := newToken(t, rotatedAt)
+
+	tests := []struct {
+		name      string
+		validator string
+		now       time.Time
+		want      bool
+	}{
+		{"at rotation", previous, rotatedAt, true},
+		{"at the end of the grace", previous, rotatedAt.Add(RotationGrace), true},
+		{"after the grace", previous, rotatedAt.Add(RotationGrace + time.Second), false},
+		{"current validator", current, rotatedAt, false},
+		{"wrong validator", previous + "x", rotatedAt, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidPrevious(token, tt.validator, tt.now); got != tt.want {
+				t.Errorf("ValidPrevious = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("never rotated", func(t *testing.T) {
+		token, value, err := New(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, validator, _ := Parse(value)
+
+		if ValidPrevious(token, validator, token.LastUsedAt) || ValidPrevious(token, "", token.LastUsedAt) {
+			t.Error("a token that was never rotated has a previous validator")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := token
+		expired.ExpiresAt = rotatedAt
+
+		if ValidPrevious(expired, previous, rotatedAt) {
+			t.Error("previous validator accepted for an expired token")
+		}
+	})
+}
+
+func TestRotate(t *testing.T) {
+	token, value, err := New(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(time.Hour)
+	rotated, rotatedValue, err := Rotate(token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selector, validator, ok := Parse(rotatedValue)
+	if !ok || selector != token.Selector {
+		t.Errorf("rotated cookie %q, want the selector %q kept", rotatedValue, token.Selector)
+	}
+	if rotatedValue == value || rotated.ValidatorHash == token.ValidatorHash {
+		t.Error("rotation kept the validator")
+	}
+	if rotated.PreviousValidatorHash != token.ValidatorHash {
+		t.Error("rotation didn't keep the old validator hash as the previous one")
+	}
+	if !rotated.ExpiresAt.Equal(now.Add(Lifetime)) || !rotated.LastUsedAt.Equal(now) {
+		t.Errorf("expires %s and last used %s, want the lifetime started again at %s", rotated.ExpiresAt, rotated.LastUsedAt, now)
+	}
+	if rotated.UserID != 7 {
+		t.Errorf("user id = %d, want 7", rotated.UserID)
+	}
+	if rotated.ValidatorHash == validator {
+		t.Error("the validator is stored, not its hash")
+	}
+}
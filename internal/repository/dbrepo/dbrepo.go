@@ -1,22 +1,135 @@
 package dbrepo
 
 import (
+	"context"
 	"database/sql"
 	"server_monitor/internal/config"
+	"server_monitor/internal/driver"
 	"server_monitor/internal/repository"
+	"time"
 )
 
 var app *config.AppConfig
 
-type mysqlDBRepo struct {
+type sqlDBRepo struct {
 	App *config.AppConfig
-	DB  *sql.DB
+	DB  *sqlDB
 }
 
 func NewMysqlRepo(Conn *sql.DB, a *config.AppConfig) repository.DatabaseRepo {
 	app = a
-	return &mysqlDBRepo{
+	return &sqlDBRepo{
 		App: a,
-		DB:  Conn,
+		DB:  &sqlDB{DB: Conn, dbType: driver.MySQL},
 	}
 }
+
+// NewPostgresRepo returns a repository for a PostgreSQL database
+func NewPostgresRepo(Conn *sql.DB, a *config.AppConfig) repository.DatabaseRepo {
+	app = a
+	return &sqlDBRepo{
+		App: a,
+		DB:  &sqlDB{DB: Conn, dbType: driver.Postgres},
+	}
+}
+
+// NewSqliteRepo returns a repository for a SQLite database
+func NewSqliteRepo(Conn *sql.DB, a *config.AppConfig) repository.DatabaseRepo {
+	app = a
+	return &sqlDBRepo{
+		App: a,
+		DB:  &sqlDB{DB: Conn, dbType: driver.SQLite},
+	}
+}
+
+// sqlDB wraps a database so the queries in this package can be written once with ? placeholders.
+// For postgres they are rewritten to $1, $2, ... and for sqlite, which stores times as text,
+// times are written in UTC so that comparing them as strings gives the right order.
+type sqlDB struct {
+	*sql.DB
+	dbType string
+}
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.rebind(query), db.convert(args)...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.rebind(query), db.convert(args)...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.rebind(query), db.convert(args)...)
+}
+
+// BeginTx starts a transaction whose queries are rewritten the same way
+func (db *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlTx{Tx: tx, db: db}, nil
+}
+
+// sqlTx is a transaction on a sqlDB
+type sqlTx struct {
+	*sql.Tx
+	db *sqlDB
+}
+
+func (tx *sqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.db.rebind(query), tx.db.convert(args)...)
+}
+
+func (tx *sqlTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.db.rebind(query), tx.db.convert(args)...)
+}
+
+func (tx *sqlTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.db.rebind(query), tx.db.convert(args)...)
+}
+
+// convert returns args with times in UTC when the database stores them as text
+func (db *sqlDB) convert(args []interface{}) []interface{} {
+	if db.dbType != driver.SQLite {
+		return args
+	}
+
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC()
+		case sql.NullTime:
+			v.Time = v.Time.UTC()
+			converted[i] = v
+		default:
+			converted[i] = arg
+		}
+	}
+
+	return converted
+}
+
+// insertID runs an INSERT and returns the id of the new row
+func (db *sqlDB) insertID(ctx context.Context, query string, args ...interface{}) (int, error) {
+	if db.dbType == driver.Postgres {
+		var id int
+		err := db.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// rebind replaces the ? placeholders in query with the ones the database needs
+func (db *sqlDB) rebind(query string) string {
+	return driver.Rebind(db.dbType, query)
+}
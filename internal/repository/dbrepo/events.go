@@ -0,0 +1,126 @@
+package dbrepo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"server_monitor/internal/models"
+	"strings"
+	"time"
+)
+
+// InsertEvent adds a new record to the events table
+func (repo *sqlDBRepo) InsertEvent(e models.Event) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	stmt := `INSERT INTO events (host_service_id, host_id, service_id, host_name, service_name, old_status, new_status,
+				message, duration_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt,
+		e.HostServiceID, e.HostID, e.ServiceID, e.HostName, e.ServiceName, e.OldStatus, e.NewStatus,
+		e.Message, e.Duration.Milliseconds(), e.CreatedAt)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// GetEvents returns one page of events matching the filter, newest first, and the total number of matches
+func (repo *sqlDBRepo) GetEvents(f models.EventFilter) ([]models.Event, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var where []string
+	var args []interface{}
+
+	if f.HostID > 0 {
+		where = append(where, "host_id = ?")
+		args = append(args, f.HostID)
+	}
+	if f.ServiceID > 0 {
+		where = append(where, "service_id = ?")
+		args = append(args, f.ServiceID)
+	}
+	if f.Status != "" {
+		where = append(where, "new_status = ?")
+		args = append(args, f.Status)
+	}
+	if !f.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.To)
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	row := repo.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(id) FROM events %s", whereClause), args...)
+	if err := row.Scan(&total); err != nil {
+		log.Println(err)
+		return nil, 0, err
+	}
+
+	if f.PerPage < 1 {
+		f.PerPage = 50
+	}
+	if f.Page < 1 {
+		f.Page = 1
+	}
+
+	stmt := fmt.Sprintf(`SELECT id, host_service_id, host_id, service_id, host_name, service_name, old_status,
+				new_status, message, duration_ms, created_at
+				FROM events %s ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, whereClause)
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+
+	for rows.Next() {
+		var e models.Event
+		var durationMS int64
+
+		err = rows.Scan(
+			&e.ID,
+			&e.HostServiceID,
+			&e.HostID,
+			&e.ServiceID,
+			&e.HostName,
+			&e.ServiceName,
+			&e.OldStatus,
+			&e.NewStatus,
+			&e.Message,
+			&durationMS,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
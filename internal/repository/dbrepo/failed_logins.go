@@ -0,0 +1,150 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"server_monitor/internal/models"
+	"time"
+)
+
+// failedLoginRetention is how long failed logins are kept
+const failedLoginRetention = 30 * 24 * time.Hour
+
+// InsertFailedLogin records a failed login, for the user with its email if there is one, returning its id,
+// and removes failed logins older than failedLoginRetention
+func (repo *sqlDBRepo) InsertFailedLogin(f models.FailedLogin) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, "DELETE FROM failed_logins WHERE attempted_at < ?", time.Now().Add(-failedLoginRetention))
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	stmt := `INSERT INTO failed_logins (email, user_id, ip_address, attempted_at, cleared)
+				VALUES (?, COALESCE((SELECT MIN(id) FROM users WHERE LOWER(email) = ? AND deleted_at IS NULL), 0), ?, ?, 0)`
+
+	newID, err := repo.DB.insertID(ctx, stmt, f.Email, f.Email, f.IPAddress, f.AttemptedAt)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// DeleteFailedLogin removes a failed login by id
+func (repo *sqlDBRepo) DeleteFailedLogin(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, "DELETE FROM failed_logins WHERE id = ?", id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// AccountLoginFailures returns the failed logins to an email address since a time, that haven't been
+// cleared by a successful login, leaving out the one with id exceptID
+func (repo *sqlDBRepo) AccountLoginFailures(email string, since time.Time, exceptID int) (models.LoginFailures, error) {
+	return repo.loginFailures("email = ? AND cleared = 0", email, since, exceptID)
+}
+
+// IPLoginFailures returns the failed logins from an IP address since a time, leaving out the one with id exceptID
+func (repo *sqlDBRepo) IPLoginFailures(ipAddress string, since time.Time, exceptID int) (models.LoginFailures, error) {
+	return repo.loginFailures("ip_address = ?", ipAddress, since, exceptID)
+}
+
+// loginFailures counts the failed logins matching where, which takes one argument, since a time
+func (repo *sqlDBRepo) loginFailures(where string, arg interface{}, since time.Time, exceptID int) (models.LoginFailures, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var failures models.LoginFailures
+
+	stmt := `SELECT attempted_at FROM failed_logins WHERE ` + where + ` AND attempted_at > ? AND id <> ?
+			ORDER BY attempted_at DESC`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, arg, since, exceptID)
+	if err != nil {
+		log.Println(err)
+		return failures, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attemptedAt time.Time
+		if err = rows.Scan(&attemptedAt); err != nil {
+			log.Println(err)
+			return failures, err
+		}
+
+		if failures.Count == 0 {
+			failures.Last = attemptedAt
+		}
+		failures.Count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return failures, err
+	}
+
+	return failures, nil
+}
+
+// ClearFailedLogins marks the failed logins to an email address cleared, after a successful login
+func (repo *sqlDBRepo) ClearFailedLogins(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, "UPDATE failed_logins SET cleared = 1 WHERE email = ? AND cleared = 0", email)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// FailedLoginsForUser returns the latest failed logins to a user's account, newest first
+func (repo *sqlDBRepo) FailedLoginsForUser(userID, limit int) ([]models.FailedLogin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, email, user_id, ip_address, attempted_at, cleared
+			FROM failed_logins WHERE user_id = ? ORDER BY attempted_at DESC, id DESC LIMIT ?`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, userID, limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failedLogins []models.FailedLogin
+
+	for rows.Next() {
+		var f models.FailedLogin
+		var cleared int
+		err = rows.Scan(&f.ID, &f.Email, &f.UserID, &f.IPAddress, &f.AttemptedAt, &cleared)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		f.Cleared = cleared == 1
+
+		failedLogins = append(failedLogins, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return failedLogins, nil
+}
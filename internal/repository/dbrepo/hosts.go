@@ -0,0 +1,697 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"server_monitor/internal/models"
+	"time"
+)
+
+// InsertHost adds a new record to the hosts table
+func (repo *sqlDBRepo) InsertHost(h models.Host) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO hosts (host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt,
+		h.HostName, h.CanonicalName, h.URL, h.IP, h.IPV6, h.Location, h.OS, h.Active, time.Now(), time.Now())
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateHost updates a host by id
+func (repo *sqlDBRepo) UpdateHost(h models.Host) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE hosts SET host_name = ?, canonical_name = ?, url = ?, ip = ?, ipv6 = ?, location = ?, os = ?,
+				active = ?, updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt,
+		h.HostName, h.CanonicalName, h.URL, h.IP, h.IPV6, h.Location, h.OS, h.Active, time.Now(), h.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetHostByID returns a host, with its services, by id
+func (repo *sqlDBRepo) GetHostByID(id int) (models.Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at
+				FROM hosts WHERE id = ? AND deleted_at IS NULL`
+
+	row := repo.DB.QueryRowContext(ctx, stmt, id)
+
+	var h models.Host
+
+	err := row.Scan(
+		&h.ID,
+		&h.HostName,
+		&h.CanonicalName,
+		&h.URL,
+		&h.IP,
+		&h.IPV6,
+		&h.Location,
+		&h.OS,
+		&h.Active,
+		&h.CreatedAt,
+		&h.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return h, models.ErrNoRecord
+	} else if err != nil {
+		log.Println(err)
+		return h, err
+	}
+
+	h.HostServices, err = repo.GetHostServicesByHostID(h.ID)
+	if err != nil {
+		return h, err
+	}
+
+	return h, nil
+}
+
+// AllHosts returns all hosts, with their services
+func (repo *sqlDBRepo) AllHosts() ([]*models.Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at
+				FROM hosts WHERE deleted_at IS NULL ORDER BY host_name`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hosts []*models.Host
+
+	for rows.Next() {
+		h := &models.Host{}
+		err = rows.Scan(
+			&h.ID,
+			&h.HostName,
+			&h.CanonicalName,
+			&h.URL,
+			&h.IP,
+			&h.IPV6,
+			&h.Location,
+			&h.OS,
+			&h.Active,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	for _, h := range hosts {
+		h.HostServices, err = repo.GetHostServicesByHostID(h.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hosts, nil
+}
+
+// DeleteHost sets a host, and its services, to deleted by populating deleted_at value
+func (repo *sqlDBRepo) DeleteHost(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE hosts SET deleted_at = ?, active = 0 WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	stmt = `UPDATE host_services SET deleted_at = ?, active = 0 WHERE host_id = ?`
+
+	_, err = repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// InsertService adds a new record to the services table
+func (repo *sqlDBRepo) InsertService(s models.Service) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO services (service_name, active, icon, check_type, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt, s.ServiceName, s.Active, s.Icon, s.CheckType, time.Now(), time.Now())
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateService updates a service by id
+func (repo *sqlDBRepo) UpdateService(s models.Service) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE services SET service_name = ?, active = ?, icon = ?, check_type = ?, updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, s.ServiceName, s.Active, s.Icon, s.CheckType, time.Now(), s.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetServiceByID returns a service by id
+func (repo *sqlDBRepo) GetServiceByID(id int) (models.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, service_name, active, icon, check_type, created_at, updated_at
+				FROM services WHERE id = ? AND deleted_at IS NULL`
+
+	row := repo.DB.QueryRowContext(ctx, stmt, id)
+
+	var s models.Service
+
+	err := row.Scan(&s.ID, &s.ServiceName, &s.Active, &s.Icon, &s.CheckType, &s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return s, models.ErrNoRecord
+	} else if err != nil {
+		log.Println(err)
+		return s, err
+	}
+
+	return s, nil
+}
+
+// AllServices returns all services
+func (repo *sqlDBRepo) AllServices() ([]*models.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, service_name, active, icon, check_type, created_at, updated_at
+				FROM services WHERE deleted_at IS NULL ORDER BY service_name`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []*models.Service
+
+	for rows.Next() {
+		s := &models.Service{}
+		err = rows.Scan(&s.ID, &s.ServiceName, &s.Active, &s.Icon, &s.CheckType, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		services = append(services, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// AllServiceNames returns the names of all services, including deleted ones
+func (repo *sqlDBRepo) AllServiceNames() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := repo.DB.QueryContext(ctx, `SELECT service_name FROM services`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// DeleteService sets a service, and the host services using it, to deleted by populating deleted_at value
+func (repo *sqlDBRepo) DeleteService(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE services SET deleted_at = ?, active = 0 WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	stmt = `UPDATE host_services SET deleted_at = ?, active = 0 WHERE service_id = ?`
+
+	_, err = repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// InsertHostService adds a new record to the host_services table
+func (repo *sqlDBRepo) InsertHostService(hs models.HostService) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if hs.Status == "" {
+		hs.Status = "pending"
+	}
+
+	checkConfig, err := marshalCheckConfig(hs.CheckConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO host_services (host_id, service_id, active, schedule_number, schedule_unit, status,
+				last_message, check_config, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt,
+		hs.HostID, hs.ServiceID, hs.Active, hs.ScheduleNumber, hs.ScheduleUnit, hs.Status, hs.LastMessage, checkConfig,
+		time.Now(), time.Now())
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateHostService updates the settings of a host service by id. It doesn't touch the columns a
+// check writes, which are saved with UpdateHostServiceStatus, so a check that finishes meanwhile isn't lost.
+func (repo *sqlDBRepo) UpdateHostService(hs models.HostService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	checkConfig, err := marshalCheckConfig(hs.CheckConfig)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE host_services SET host_id = ?, service_id = ?, active = ?, schedule_number = ?, schedule_unit = ?,
+				check_config = ?, updated_at = ? WHERE id = ?`
+
+	_, err = repo.DB.ExecContext(ctx, stmt,
+		hs.HostID, hs.ServiceID, hs.Active, hs.ScheduleNumber, hs.ScheduleUnit, checkConfig, time.Now(), hs.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateHostServiceStatus saves the result of a check on a host service: its status, last check, last message
+// and certificate. Settings changed while the check ran are left alone.
+func (repo *sqlDBRepo) UpdateHostServiceStatus(hs models.HostService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var lastCheck, certExpiry sql.NullTime
+	if !hs.LastCheck.IsZero() {
+		lastCheck = sql.NullTime{Time: hs.LastCheck, Valid: true}
+	}
+	if !hs.CertExpiry.IsZero() {
+		certExpiry = sql.NullTime{Time: hs.CertExpiry, Valid: true}
+	}
+
+	stmt := `UPDATE host_services SET status = ?, last_check = ?, last_message = ?, cert_expiry = ?, cert_issuer = ?,
+				updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt,
+		hs.Status, lastCheck, hs.LastMessage, certExpiry, hs.CertIssuer, time.Now(), hs.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// SetHostServiceActive pauses or resumes the checks of a host service, without touching its other columns
+func (repo *sqlDBRepo) SetHostServiceActive(id, active int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, `UPDATE host_services SET active = ?, updated_at = ? WHERE id = ?`, active, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetHostServiceByID returns a host service, with its service and host name, by id
+func (repo *sqlDBRepo) GetHostServiceByID(id int) (models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.status,
+				hs.last_check, hs.last_message, hs.check_config, hs.cert_expiry, hs.cert_issuer, hs.acknowledged_at,
+				hs.acknowledged_by, hs.created_at, hs.updated_at,
+				s.id, s.service_name, s.active, s.icon, s.check_type, s.created_at, s.updated_at, h.host_name
+				FROM host_services hs
+				LEFT JOIN services s ON (s.id = hs.service_id)
+				LEFT JOIN hosts h ON (h.id = hs.host_id)
+				WHERE hs.id = ? AND hs.deleted_at IS NULL`
+
+	row := repo.DB.QueryRowContext(ctx, stmt, id)
+
+	hs, err := scanHostService(row)
+	if err == sql.ErrNoRows {
+		return hs, models.ErrNoRecord
+	} else if err != nil {
+		log.Println(err)
+		return hs, err
+	}
+
+	return hs, nil
+}
+
+// GetHostServicesByHostID returns all services for a host
+func (repo *sqlDBRepo) GetHostServicesByHostID(hostID int) ([]models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.status,
+				hs.last_check, hs.last_message, hs.check_config, hs.cert_expiry, hs.cert_issuer, hs.acknowledged_at,
+				hs.acknowledged_by, hs.created_at, hs.updated_at,
+				s.id, s.service_name, s.active, s.icon, s.check_type, s.created_at, s.updated_at, h.host_name
+				FROM host_services hs
+				LEFT JOIN services s ON (s.id = hs.service_id)
+				LEFT JOIN hosts h ON (h.id = hs.host_id)
+				WHERE hs.host_id = ? AND hs.deleted_at IS NULL
+				ORDER BY s.service_name`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hostServices []models.HostService
+
+	for rows.Next() {
+		hs, err := scanHostService(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		hostServices = append(hostServices, hs)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return hostServices, nil
+}
+
+// GetServicesToMonitor returns all active host services on active hosts
+func (repo *sqlDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.status,
+				hs.last_check, hs.last_message, hs.check_config, hs.cert_expiry, hs.cert_issuer, hs.acknowledged_at,
+				hs.acknowledged_by, hs.created_at, hs.updated_at,
+				s.id, s.service_name, s.active, s.icon, s.check_type, s.created_at, s.updated_at, h.host_name
+				FROM host_services hs
+				LEFT JOIN services s ON (s.id = hs.service_id)
+				LEFT JOIN hosts h ON (h.id = hs.host_id)
+				WHERE hs.active = 1 AND h.active = 1 AND s.active = 1
+				AND hs.deleted_at IS NULL AND h.deleted_at IS NULL AND s.deleted_at IS NULL`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hostServices []models.HostService
+
+	for rows.Next() {
+		hs, err := scanHostService(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		hostServices = append(hostServices, hs)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return hostServices, nil
+}
+
+// GetAllServiceStatusCounts returns the number of monitored host services that are pending, healthy, warning and problem
+func (repo *sqlDBRepo) GetAllServiceStatusCounts() (int, int, int, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT
+				COALESCE(SUM(CASE WHEN hs.status = 'pending' THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN hs.status = 'healthy' THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN hs.status = 'warning' THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN hs.status = 'problem' THEN 1 ELSE 0 END), 0)
+				FROM host_services hs
+				LEFT JOIN hosts h ON (h.id = hs.host_id)
+				WHERE hs.active = 1 AND h.active = 1 AND hs.deleted_at IS NULL AND h.deleted_at IS NULL`
+
+	var pending, healthy, warning, problem int
+
+	row := repo.DB.QueryRowContext(ctx, stmt)
+	err := row.Scan(&pending, &healthy, &warning, &problem)
+	if err != nil {
+		log.Println(err)
+		return 0, 0, 0, 0, err
+	}
+
+	return pending, healthy, warning, problem, nil
+}
+
+// GetServicesByStatus returns all monitored host services with a status
+func (repo *sqlDBRepo) GetServicesByStatus(status string) ([]models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.status,
+				hs.last_check, hs.last_message, hs.check_config, hs.cert_expiry, hs.cert_issuer, hs.acknowledged_at,
+				hs.acknowledged_by, hs.created_at, hs.updated_at,
+				s.id, s.service_name, s.active, s.icon, s.check_type, s.created_at, s.updated_at, h.host_name
+				FROM host_services hs
+				LEFT JOIN services s ON (s.id = hs.service_id)
+				LEFT JOIN hosts h ON (h.id = hs.host_id)
+				WHERE hs.status = ? AND hs.active = 1 AND h.active = 1 AND hs.deleted_at IS NULL AND h.deleted_at IS NULL
+				ORDER BY h.host_name, s.service_name`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hostServices []models.HostService
+
+	for rows.Next() {
+		hs, err := scanHostService(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		hostServices = append(hostServices, hs)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return hostServices, nil
+}
+
+// DeleteHostService sets a host service to deleted by populating deleted_at value
+func (repo *sqlDBRepo) DeleteHostService(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET deleted_at = ?, active = 0 WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// AcknowledgeHostService records that userName has seen the current status of a host service
+func (repo *sqlDBRepo) AcknowledgeHostService(id int, userName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET acknowledged_at = ?, acknowledged_by = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, time.Now(), userName, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// ClearAcknowledgement removes the acknowledgement of a host service, when its status changes
+func (repo *sqlDBRepo) ClearAcknowledgement(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET acknowledged_at = NULL, acknowledged_by = '' WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanHostService scans a host_services row joined with services and hosts
+func scanHostService(row rowScanner) (models.HostService, error) {
+	var hs models.HostService
+	var lastCheck, certExpiry, acknowledgedAt sql.NullTime
+	var checkConfig string
+
+	err := row.Scan(
+		&hs.ID,
+		&hs.HostID,
+		&hs.ServiceID,
+		&hs.Active,
+		&hs.ScheduleNumber,
+		&hs.ScheduleUnit,
+		&hs.Status,
+		&lastCheck,
+		&hs.LastMessage,
+		&checkConfig,
+		&certExpiry,
+		&hs.CertIssuer,
+		&acknowledgedAt,
+		&hs.AcknowledgedBy,
+		&hs.CreatedAt,
+		&hs.UpdatedAt,
+		&hs.Service.ID,
+		&hs.Service.ServiceName,
+		&hs.Service.Active,
+		&hs.Service.Icon,
+		&hs.Service.CheckType,
+		&hs.Service.CreatedAt,
+		&hs.Service.UpdatedAt,
+		&hs.HostName,
+	)
+	if err != nil {
+		return hs, err
+	}
+
+	if lastCheck.Valid {
+		hs.LastCheck = lastCheck.Time
+	}
+	if certExpiry.Valid {
+		hs.CertExpiry = certExpiry.Time
+	}
+	if acknowledgedAt.Valid {
+		hs.AcknowledgedAt = acknowledgedAt.Time
+	}
+
+	hs.CheckConfig = make(map[string]string)
+	if checkConfig != "" {
+		err = json.Unmarshal([]byte(checkConfig), &hs.CheckConfig)
+		if err != nil {
+			return hs, err
+		}
+	}
+
+	return hs, nil
+}
+
+// marshalCheckConfig returns the json stored in host_services.check_config
+func marshalCheckConfig(cfg map[string]string) (string, error) {
+	if cfg == nil {
+		cfg = make(map[string]string)
+	}
+
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,234 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"server_monitor/internal/models"
+	"time"
+)
+
+const queuedMailColumns = `id, status, attempts, last_error, next_attempt_at, sent_at, message, created_at, updated_at`
+
+// scanQueuedMail reads one row selected with queuedMailColumns
+func scanQueuedMail(row rowScanner) (models.QueuedMail, error) {
+	var m models.QueuedMail
+	var sentAt sql.NullTime
+	var message []byte
+
+	err := row.Scan(
+		&m.ID,
+		&m.Status,
+		&m.Attempts,
+		&m.LastError,
+		&m.NextAttemptAt,
+		&sentAt,
+		&message,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		return m, err
+	}
+
+	if sentAt.Valid {
+		m.SentAt = sentAt.Time
+	}
+
+	if err = json.Unmarshal(message, &m.Message); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// InsertQueuedMail adds a new record to the mail_queue table
+func (repo *sqlDBRepo) InsertQueuedMail(m models.QueuedMail) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	message, err := json.Marshal(m.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = time.Now()
+	}
+
+	stmt := `INSERT INTO mail_queue (status, attempts, last_error, next_attempt_at, to_address, subject, message,
+				created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt,
+		m.Status, m.Attempts, m.LastError, m.NextAttemptAt, m.Message.ToAddress, m.Message.Subject, message,
+		time.Now(), time.Now())
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateQueuedMail updates the delivery state of a mail_queue record
+func (repo *sqlDBRepo) UpdateQueuedMail(m models.QueuedMail) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var sentAt sql.NullTime
+	if !m.SentAt.IsZero() {
+		sentAt = sql.NullTime{Time: m.SentAt, Valid: true}
+	}
+
+	stmt := `UPDATE mail_queue SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, sent_at = ?,
+				updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt,
+		m.Status, m.Attempts, m.LastError, m.NextAttemptAt, sentAt, time.Now(), m.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetQueuedMailByID returns one mail_queue record by id
+func (repo *sqlDBRepo) GetQueuedMailByID(id int) (models.QueuedMail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	row := repo.DB.QueryRowContext(ctx, `SELECT `+queuedMailColumns+` FROM mail_queue WHERE id = ?`, id)
+
+	m, err := scanQueuedMail(row)
+	if err == sql.ErrNoRows {
+		return m, models.ErrNoRecord
+	}
+	if err != nil {
+		log.Println(err)
+		return m, err
+	}
+
+	return m, nil
+}
+
+// GetQueuedMails returns one page of mail_queue records with status, or any status if it is empty,
+// newest first, and the total number of matches
+func (repo *sqlDBRepo) GetQueuedMails(status string, page, perPage int) ([]models.QueuedMail, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	where := ""
+	var args []interface{}
+	if status != "" {
+		where = "WHERE status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	row := repo.DB.QueryRowContext(ctx, "SELECT COUNT(id) FROM mail_queue "+where, args...)
+	if err := row.Scan(&total); err != nil {
+		log.Println(err)
+		return nil, 0, err
+	}
+
+	if perPage < 1 {
+		perPage = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	stmt := `SELECT ` + queuedMailColumns + ` FROM mail_queue ` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt, append(args, perPage, (page-1)*perPage)...)
+	if err != nil {
+		log.Println(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var mails []models.QueuedMail
+
+	for rows.Next() {
+		m, err := scanQueuedMail(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, 0, err
+		}
+		mails = append(mails, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, 0, err
+	}
+
+	return mails, total, nil
+}
+
+// ClaimDueMail marks up to limit queued mails that are due as sending, and returns them
+func (repo *sqlDBRepo) ClaimDueMail(limit int) ([]models.QueuedMail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := repo.DB.QueryContext(ctx, `SELECT `+queuedMailColumns+` FROM mail_queue
+			WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		models.MailQueued, time.Now(), limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var due []models.QueuedMail
+	for rows.Next() {
+		m, err := scanQueuedMail(rows)
+		if err != nil {
+			rows.Close()
+			log.Println(err)
+			return nil, err
+		}
+		due = append(due, m)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var claimed []models.QueuedMail
+
+	for _, m := range due {
+		// only claim mail that nothing else has claimed since it was selected
+		result, err := repo.DB.ExecContext(ctx, `UPDATE mail_queue SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+			models.MailSending, time.Now(), m.ID, models.MailQueued)
+		if err != nil {
+			log.Println(err)
+			return claimed, err
+		}
+
+		if n, _ := result.RowsAffected(); n == 1 {
+			m.Status = models.MailSending
+			claimed = append(claimed, m)
+		}
+	}
+
+	return claimed, nil
+}
+
+// RequeueSendingMail puts mail that was being sent when the app last stopped back in the queue
+func (repo *sqlDBRepo) RequeueSendingMail() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, `UPDATE mail_queue SET status = ?, updated_at = ? WHERE status = ?`,
+		models.MailQueued, time.Now(), models.MailSending)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
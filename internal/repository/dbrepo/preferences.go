@@ -3,11 +3,14 @@ package dbrepo
 import (
 	"context"
 	"log"
+	"server_monitor/internal/driver"
 	"server_monitor/internal/models"
+	"server_monitor/internal/preferences"
+	"sort"
 	"time"
 )
 
-func (repo *mysqlDBRepo) AllPreferences() ([]models.Preference, error) {
+func (repo *sqlDBRepo) AllPreferences() ([]models.Preference, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -37,44 +40,87 @@ func (repo *mysqlDBRepo) AllPreferences() ([]models.Preference, error) {
 	return preferences, nil
 }
 
-func (repo *mysqlDBRepo) SetSystemPref(name, value string) error {
+// InsertOrUpdateSitePreferences saves the preferences in pm in one transaction. Each value that
+// changes is also written to the preference_audit table as changed by user, without the values of secrets.
+func (repo *sqlDBRepo) InsertOrUpdateSitePreferences(pm map[string]string, user models.User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := `delete from preferences where name = $1`
-	_, _ = repo.DB.ExecContext(ctx, stmt, name)
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
 
-	query := `INSERT INTO preferences (
-				name, preference, created_at, updated_at) 
-				VALUES ($1, $2, $3, $4)`
-	_, err := repo.DB.ExecContext(ctx, query, name, value, time.Now(), time.Now())
+	current := make(map[string]string)
 
+	rows, err := tx.QueryContext(ctx, "SELECT name, preference FROM preferences")
 	if err != nil {
 		log.Println(err)
 		return err
 	}
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			log.Println(err)
+			return err
+		}
+		current[name] = value
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return err
+	}
 
-	return nil
-}
+	// save in a fixed order, so concurrent saves lock rows in the same order
+	names := make([]string, 0, len(pm))
+	for name := range pm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-func (repo *mysqlDBRepo) InsertOrUpdateSitePreferences(pm map[string]string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	now := time.Now()
+	upsert := repo.upsertPreferenceQuery()
 
-	for k, v := range pm {
-		query := `DELETE FROM preferences WHERE name = $1`
-		_, err := repo.DB.ExecContext(ctx, query, k)
-		if err != nil {
-			return err
+	for _, name := range names {
+		value := pm[name]
+		old, exists := current[name]
+		if exists && old == value {
+			continue
 		}
 
-		query = `INSERT INTO preferences (name, preference, created_at, updated_at)
-					VALUES ($1, $2, $3, $4)`
+		if _, err = tx.ExecContext(ctx, upsert, name, value, now, now); err != nil {
+			log.Println(err)
+			return err
+		}
 
-		_, err = repo.DB.ExecContext(ctx, query, k, v, time.Now(), time.Now())
+		_, err = tx.ExecContext(ctx, `INSERT INTO preference_audit (name, old_value, new_value, user_id, changed_by, changed_at)
+				VALUES (?, ?, ?, ?, ?, ?)`, name, preferences.AuditValue(name, old), preferences.AuditValue(name, value),
+			user.ID, user.Name, now)
 		if err != nil {
+			log.Println(err)
 			return err
 		}
 	}
+
+	if err = tx.Commit(); err != nil {
+		log.Println(err)
+		return err
+	}
+
 	return nil
 }
+
+// upsertPreferenceQuery returns the statement that inserts a preference, or updates it if the name exists
+func (repo *sqlDBRepo) upsertPreferenceQuery() string {
+	if repo.DB.dbType == driver.MySQL {
+		return `INSERT INTO preferences (name, preference, created_at, updated_at) VALUES (?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE preference = VALUES(preference), updated_at = VALUES(updated_at)`
+	}
+
+	return `INSERT INTO preferences (name, preference, created_at, updated_at) VALUES (?, ?, ?, ?)
+				ON CONFLICT (name) DO UPDATE SET preference = excluded.preference, updated_at = excluded.updated_at`
+}
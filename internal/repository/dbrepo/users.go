@@ -0,0 +1,361 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"server_monitor/internal/driver"
+	"server_monitor/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// passwordCost is the bcrypt cost of stored passwords
+const passwordCost = 12
+
+// GetUserById returns a user by id
+func (repo *sqlDBRepo) GetUserById(id int) (models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, name, user_active, access_level, email, created_at, updated_at, signed_out_at FROM users WHERE id = ?`
+
+	row := repo.DB.QueryRowContext(ctx, stmt, id)
+
+	var u models.User
+	var signedOutAt sql.NullTime
+
+	err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.UserActive,
+		&u.AccessLevel,
+		&u.Email,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&signedOutAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return u, models.ErrNoRecord
+	} else if err != nil {
+		log.Println(err)
+		return u, err
+	}
+
+	u.SignedOutAt = signedOutAt.Time
+
+	return u, nil
+}
+
+// InsertUser adds a new record to the users table
+func (repo *sqlDBRepo) InsertUser(u models.User) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword(u.Password, passwordCost)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO users (name, email, password, access_level, user_active, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	newID, err := repo.DB.insertID(ctx, stmt,
+		u.Name, normalizeEmail(u.Email), hashedPassword, u.AccessLevel, u.UserActive, time.Now(), time.Now())
+	if driver.IsUniqueViolation(err) {
+		return 0, models.ErrDuplicateEmail
+	} else if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateUser updates a user by id
+func (repo *sqlDBRepo) UpdateUser(u models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE users SET name = ?, user_active = ?, email = ?, access_level = ?, updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, u.Name, u.UserActive, normalizeEmail(u.Email), u.AccessLevel, time.Now(), u.ID)
+	if driver.IsUniqueViolation(err) {
+		return models.ErrDuplicateEmail
+	} else if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// normalizeEmail returns email as it is stored, in lower case, so that the unique index on users and
+// logging in don't depend on how the address was capitalised
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// DeleteUser sets a user to deleted by populating deleted_at value
+func (repo *sqlDBRepo) DeleteUser(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE users SET deleted_at = ?, user_active = 0 WHERE id = ?`
+
+	_, err := repo.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePassword resets a password
+func (repo *sqlDBRepo) UpdatePassword(id int, newPassword string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), passwordCost)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	stmt := `UPDATE users SET password = ?, updated_at = ? WHERE id = ?`
+
+	_, err = repo.DB.ExecContext(ctx, stmt, hashedPassword, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate checks an email and password, and returns the user's id and password hash
+func (repo *sqlDBRepo) Authenticate(email, testPassword string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int
+	var hashedPassword string
+	var userActive int
+
+	query := `SELECT id, password, user_active FROM users WHERE email = ? AND deleted_at IS NULL`
+
+	row := repo.DB.QueryRowContext(ctx, query, normalizeEmail(email))
+	err := row.Scan(&id, &hashedPassword, &userActive)
+
+	if err == sql.ErrNoRows {
+		// take as long as a wrong password would, so the time taken doesn't say whether the email has an account
+		_ = bcrypt.CompareHashAndPassword(missingUserHash(), []byte(testPassword))
+		return 0, "", models.ErrInvalidCredentials
+	} else if err != nil {
+		log.Println(err)
+		return 0, "", err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(testPassword))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return 0, "", models.ErrInvalidCredentials
+	} else if err != nil {
+		log.Println(err)
+		return 0, "", err
+	}
+
+	// only said once the password is right, so it doesn't give away which accounts exist
+	if userActive == 0 {
+		return 0, "", models.ErrInactiveAccount
+	}
+
+	return id, hashedPassword, nil
+}
+
+var (
+	missingUserHashOnce sync.Once
+	missingUserHashed   []byte
+)
+
+// missingUserHash returns a bcrypt hash with the same cost as stored passwords, to compare against
+// when there is no user
+func missingUserHash() []byte {
+	missingUserHashOnce.Do(func() {
+		missingUserHashed, _ = bcrypt.GenerateFromPassword([]byte("no user has this password"), passwordCost)
+	})
+
+	return missingUserHashed
+}
+
+// AllUsers returns all user
+func (repo *sqlDBRepo) AllUsers() ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, name, email, user_active, access_level, created_at, updated_at FROM users WHERE deleted_at IS NULL`
+
+	rows, err := repo.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+
+	for rows.Next() {
+		s := &models.User{}
+		err = rows.Scan(&s.ID, &s.Name, &s.Email, &s.UserActive, &s.AccessLevel, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// InsertRememberToken adds a remember me token, and removes the user's expired ones
+func (repo *sqlDBRepo) InsertRememberToken(t models.RememberToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, "DELETE FROM remember_tokens WHERE user_id = ? AND expires_at <= ?", t.UserID, time.Now())
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	stmt := `INSERT INTO remember_tokens (user_id, selector, validator_hash, previous_validator_hash, expires_at,
+				created_at, last_used_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = repo.DB.ExecContext(ctx, stmt, t.UserID, t.Selector, t.ValidatorHash, t.PreviousValidatorHash, t.ExpiresAt,
+		t.CreatedAt, t.LastUsedAt)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRememberToken returns the remember me token with a selector
+func (repo *sqlDBRepo) GetRememberToken(selector string) (models.RememberToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, user_id, selector, validator_hash, previous_validator_hash, expires_at, created_at, last_used_at
+				FROM remember_tokens WHERE selector = ?`
+
+	var t models.RememberToken
+
+	err := repo.DB.QueryRowContext(ctx, stmt, selector).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Selector,
+		&t.ValidatorHash,
+		&t.PreviousValidatorHash,
+		&t.ExpiresAt,
+		&t.CreatedAt,
+		&t.LastUsedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return t, models.ErrNoRecord
+	} else if err != nil {
+		log.Println(err)
+		return t, err
+	}
+
+	return t, nil
+}
+
+// UpdateRememberToken saves a rotated remember me token. It returns models.ErrNoRecord if the
+// token was deleted, or rotated by another request, since it was read.
+//
+// The replaced validator hash is kept as previous_validator_hash, and remember.ValidPrevious accepts it
+// for remember.RotationGrace after the rotation. Without that, requests a browser sends together with the
+// old cookie would find a mismatch and delete the token, logging the user out. The cost is that a copied
+// cookie also works for those few seconds after the real one is used; after that, a mismatch on an
+// unexpired token still deletes it.
+func (repo *sqlDBRepo) UpdateRememberToken(t models.RememberToken, oldValidatorHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE remember_tokens SET validator_hash = ?, previous_validator_hash = ?, expires_at = ?, last_used_at = ?
+				WHERE id = ? AND validator_hash = ?`
+
+	result, err := repo.DB.ExecContext(ctx, stmt, t.ValidatorHash, t.PreviousValidatorHash, t.ExpiresAt, t.LastUsedAt,
+		t.ID, oldValidatorHash)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// DeleteRememberToken deletes the remember me token with a selector
+func (repo *sqlDBRepo) DeleteRememberToken(selector string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := repo.DB.ExecContext(ctx, "DELETE FROM remember_tokens WHERE selector = ?", selector)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// SignOutEverywhere deletes all of a user's remember me tokens, and records the time so their sessions end
+func (repo *sqlDBRepo) SignOutEverywhere(userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM remember_tokens WHERE user_id = ?", userID); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	// the column keeps microseconds
+	now := time.Now().Truncate(time.Microsecond)
+	if _, err = tx.ExecContext(ctx, "UPDATE users SET signed_out_at = ? WHERE id = ?", now, userID); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -1,11 +1,13 @@
 package repository
 
-import "server_monitor/internal/models"
+import (
+	"server_monitor/internal/models"
+	"time"
+)
 
 type DatabaseRepo interface {
 	AllPreferences() ([]models.Preference, error)
-	SetSystemPref(name, value string) error
-	InsertOrUpdateSitePreferences(pm map[string]string) error
+	InsertOrUpdateSitePreferences(pm map[string]string, user models.User) error
 
 	GetUserById(id int) (models.User, error)
 	InsertUser(u models.User) (int, error)
@@ -14,7 +16,51 @@ type DatabaseRepo interface {
 	UpdatePassword(id int, newPassword string) error
 	Authenticate(email, testPassword string) (int, string, error)
 	AllUsers() ([]*models.User, error)
-	InsertRememberMeToken(id int, token string) error
-	DeleteToken(token string) error
-	CheckForToken(id int, token string) bool
+	InsertRememberToken(t models.RememberToken) error
+	GetRememberToken(selector string) (models.RememberToken, error)
+	UpdateRememberToken(t models.RememberToken, oldValidatorHash string) error
+	DeleteRememberToken(selector string) error
+	SignOutEverywhere(userID int) error
+	InsertFailedLogin(f models.FailedLogin) (int, error)
+	DeleteFailedLogin(id int) error
+	AccountLoginFailures(email string, since time.Time, exceptID int) (models.LoginFailures, error)
+	IPLoginFailures(ipAddress string, since time.Time, exceptID int) (models.LoginFailures, error)
+	ClearFailedLogins(email string) error
+	FailedLoginsForUser(userID, limit int) ([]models.FailedLogin, error)
+
+	InsertHost(h models.Host) (int, error)
+	UpdateHost(h models.Host) error
+	GetHostByID(id int) (models.Host, error)
+	AllHosts() ([]*models.Host, error)
+	DeleteHost(id int) error
+
+	InsertService(s models.Service) (int, error)
+	UpdateService(s models.Service) error
+	GetServiceByID(id int) (models.Service, error)
+	AllServices() ([]*models.Service, error)
+	AllServiceNames() ([]string, error)
+	DeleteService(id int) error
+
+	InsertHostService(hs models.HostService) (int, error)
+	UpdateHostService(hs models.HostService) error
+	UpdateHostServiceStatus(hs models.HostService) error
+	SetHostServiceActive(id, active int) error
+	GetHostServiceByID(id int) (models.HostService, error)
+	GetHostServicesByHostID(hostID int) ([]models.HostService, error)
+	DeleteHostService(id int) error
+	GetServicesToMonitor() ([]models.HostService, error)
+	GetAllServiceStatusCounts() (int, int, int, int, error)
+	GetServicesByStatus(status string) ([]models.HostService, error)
+	AcknowledgeHostService(id int, userName string) error
+	ClearAcknowledgement(id int) error
+
+	InsertEvent(e models.Event) (int, error)
+	GetEvents(f models.EventFilter) ([]models.Event, int, error)
+
+	InsertQueuedMail(m models.QueuedMail) (int, error)
+	UpdateQueuedMail(m models.QueuedMail) error
+	GetQueuedMailByID(id int) (models.QueuedMail, error)
+	GetQueuedMails(status string, page, perPage int) ([]models.QueuedMail, int, error)
+	ClaimDueMail(limit int) ([]models.QueuedMail, error)
+	RequeueSendingMail() error
 }
@@ -0,0 +1,25 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"time"
+)
+
+// postgres keeps sessions in the table
+// sessions (token TEXT PRIMARY KEY, data BYTEA NOT NULL, expiry TIMESTAMPTZ NOT NULL)
+var postgres = dialect{
+	find: "SELECT data FROM sessions WHERE token = $1 AND expiry > $2",
+	commit: `INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3)
+			ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry`,
+	delete:  "DELETE FROM sessions WHERE token = $1",
+	cleanup: "DELETE FROM sessions WHERE expiry <= $1",
+	expiry: func(t time.Time) interface{} {
+		return t.UTC()
+	},
+}
+
+// NewPostgres returns a store for a postgres database that removes expired sessions every cleanupInterval.
+// A zero interval never removes them.
+func NewPostgres(db *sql.DB, cleanupInterval time.Duration) *Store {
+	return newStore(db, postgres, cleanupInterval)
+}
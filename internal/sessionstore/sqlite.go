@@ -0,0 +1,25 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"time"
+)
+
+// sqlite keeps sessions in the table
+// sessions (token TEXT PRIMARY KEY, data BLOB NOT NULL, expiry INTEGER NOT NULL), with expiry in unix seconds
+var sqlite = dialect{
+	find: "SELECT data FROM sessions WHERE token = ? AND expiry > ?",
+	commit: `INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?)
+			ON CONFLICT (token) DO UPDATE SET data = excluded.data, expiry = excluded.expiry`,
+	delete:  "DELETE FROM sessions WHERE token = ?",
+	cleanup: "DELETE FROM sessions WHERE expiry <= ?",
+	expiry: func(t time.Time) interface{} {
+		return t.Unix()
+	},
+}
+
+// NewSqlite returns a store for a sqlite database that removes expired sessions every cleanupInterval.
+// A zero interval never removes them.
+func NewSqlite(db *sql.DB, cleanupInterval time.Duration) *Store {
+	return newStore(db, sqlite, cleanupInterval)
+}
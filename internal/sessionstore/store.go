@@ -0,0 +1,87 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// dialect is how one database spells the session queries. Each query takes the current time as its
+// last argument, written with expiry, so the comparisons match how the database stores it.
+type dialect struct {
+	find    string
+	commit  string
+	delete  string
+	cleanup string
+	// expiry returns t as it is stored in the expiry column
+	expiry func(t time.Time) interface{}
+}
+
+// Store is an scs session store that keeps sessions in the sessions table, with the queries for one database
+type Store struct {
+	db          *sql.DB
+	dialect     dialect
+	stopCleanup chan bool
+}
+
+// newStore returns a store that removes expired sessions every cleanupInterval.
+// A zero interval never removes them.
+func newStore(db *sql.DB, d dialect, cleanupInterval time.Duration) *Store {
+	s := &Store{db: db, dialect: d}
+
+	if cleanupInterval > 0 {
+		s.stopCleanup = make(chan bool)
+		go s.startCleanup(cleanupInterval)
+	}
+
+	return s
+}
+
+// Find returns the data for a session token. exists is false if the token is unknown or expired.
+func (s *Store) Find(token string) ([]byte, bool, error) {
+	var b []byte
+
+	row := s.db.QueryRow(s.dialect.find, token, s.dialect.expiry(time.Now()))
+	err := row.Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Commit adds a session, or updates its data and expiry if the token exists
+func (s *Store) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := s.db.Exec(s.dialect.commit, token, b, s.dialect.expiry(expiry))
+	return err
+}
+
+// Delete removes a session
+func (s *Store) Delete(token string) error {
+	_, err := s.db.Exec(s.dialect.delete, token)
+	return err
+}
+
+func (s *Store) startCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.db.Exec(s.dialect.cleanup, s.dialect.expiry(time.Now())); err != nil {
+				log.Println(err)
+			}
+		case <-s.stopCleanup:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// StopCleanup stops removing expired sessions
+func (s *Store) StopCleanup() {
+	if s.stopCleanup != nil {
+		s.stopCleanup <- true
+	}
+}
@@ -0,0 +1,103 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"path/filepath"
+	"server_monitor/internal/driver"
+	"server_monitor/internal/migrations"
+	"testing"
+	"time"
+)
+
+// newSqliteTestDB returns a migrated sqlite database in a temporary file
+func newSqliteTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := driver.ConnectSqlite(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.SQL.Close() })
+
+	migrator, err := migrations.New(db.SQL, db.Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db.SQL
+}
+
+func TestSqliteStore(t *testing.T) {
+	s := NewSqlite(newSqliteTestDB(t), 0)
+
+	if _, found, err := s.Find("missing"); err != nil || found {
+		t.Errorf("find of an unknown token = %t, %v, want not found", found, err)
+	}
+
+	if err := s.Commit("token", []byte("first"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit("token", []byte("second"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("commit of an existing token: %s", err)
+	}
+
+	b, found, err := s.Find("token")
+	if err != nil || !found || string(b) != "second" {
+		t.Errorf("find = %q, %t, %v, want the data from the last commit", b, found, err)
+	}
+
+	if err = s.Commit("expired", []byte("old"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err = s.Find("expired"); err != nil || found {
+		t.Errorf("find of an expired token = %t, %v, want not found", found, err)
+	}
+
+	if err = s.Delete("token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err = s.Find("token"); err != nil || found {
+		t.Errorf("find of a deleted token = %t, %v, want not found", found, err)
+	}
+}
+
+func TestSqliteStoreCleanup(t *testing.T) {
+	db := newSqliteTestDB(t)
+	s := NewSqlite(db, 10*time.Millisecond)
+	defer s.StopCleanup()
+
+	if err := s.Commit("live", []byte("live"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit("expired", []byte("old"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var tokens []string
+		rows, err := db.Query("SELECT token FROM sessions")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var token string
+			if err = rows.Scan(&token); err != nil {
+				t.Fatal(err)
+			}
+			tokens = append(tokens, token)
+		}
+		_ = rows.Close()
+
+		if len(tokens) == 1 && tokens[0] == "live" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions left = %v, want only the live one", tokens)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,85 @@
+package sms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTwilioBaseURL is the address of Twilio's REST API
+const DefaultTwilioBaseURL = "https://api.twilio.com"
+
+// Twilio sends text messages through Twilio's REST API
+type Twilio struct {
+	// BaseURL is the API address; empty uses DefaultTwilioBaseURL, a local address can be used for testing
+	BaseURL    string
+	AccountSID string
+	AuthToken  string
+	From       string
+	Client     *http.Client
+}
+
+// twilioError is the body Twilio returns when it refuses a request
+type twilioError struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+	Status   int    `json:"status"`
+}
+
+// Send sends body as a text message to the number to
+func (t Twilio) Send(to, body string) error {
+	if t.AccountSID == "" || t.AuthToken == "" || t.From == "" {
+		return fmt.Errorf("twilio is not configured")
+	}
+	if to == "" {
+		return fmt.Errorf("no number to send to")
+	}
+
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultTwilioBaseURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(baseURL, "/"), url.PathEscape(t.AccountSID))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", t.From)
+	form.Set("Body", body)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	var twErr twilioError
+	if err = json.Unmarshal(respBody, &twErr); err == nil && twErr.Message != "" {
+		return fmt.Errorf("twilio error %d: %s", twErr.Code, twErr.Message)
+	}
+
+	return fmt.Errorf("twilio returned %s", resp.Status)
+}
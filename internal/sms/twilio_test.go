@@ -0,0 +1,167 @@
+package sms
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// twilioRequest is what the test server received
+type twilioRequest struct {
+	method      string
+	path        string
+	contentType string
+	user        string
+	password    string
+	authOK      bool
+	form        map[string]string
+}
+
+// newTwilioTestServer answers every request with status and body, and records the requests
+func newTwilioTestServer(t *testing.T, status int, body string) (*httptest.Server, func() []twilioRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []twilioRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := twilioRequest{
+			method:      r.Method,
+			path:        r.URL.EscapedPath(),
+			contentType: r.Header.Get("Content-Type"),
+			form:        map[string]string{},
+		}
+		req.user, req.password, req.authOK = r.BasicAuth()
+		if err := r.ParseForm(); err == nil {
+			for name := range r.PostForm {
+				req.form[name] = r.PostForm.Get(name)
+			}
+		}
+
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []twilioRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]twilioRequest(nil), requests...)
+	}
+}
+
+func TestTwilioSend(t *testing.T) {
+	srv, received := newTwilioTestServer(t, http.StatusCreated, `{"sid": "SM123", "status": "queued"}`)
+
+	tw := Twilio{
+		BaseURL:    srv.URL + "/",
+		AccountSID: "AC/123",
+		AuthToken:  "token",
+		From:       "+15550001111",
+	}
+
+	if err := tw.Send("+15552223333", "PROBLEM: Web on host1 - connection refused & retried"); err != nil {
+		t.Fatalf("send: %s", err)
+	}
+
+	requests := received()
+	if len(requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	if req.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.method)
+	}
+	if want := "/2010-04-01/Accounts/AC%2F123/Messages.json"; req.path != want {
+		t.Errorf("path = %s, want %s", req.path, want)
+	}
+	if req.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("content type = %q, want a url encoded form", req.contentType)
+	}
+	if !req.authOK || req.user != "AC/123" || req.password != "token" {
+		t.Errorf("basic auth = %q:%q (%t), want the account sid and auth token", req.user, req.password, req.authOK)
+	}
+
+	wantForm := map[string]string{
+		"To":   "+15552223333",
+		"From": "+15550001111",
+		"Body": "PROBLEM: Web on host1 - connection refused & retried",
+	}
+	if len(req.form) != len(wantForm) {
+		t.Errorf("form = %v, want %v", req.form, wantForm)
+	}
+	for name, value := range wantForm {
+		if req.form[name] != value {
+			t.Errorf("form %s = %q, want %q", name, req.form[name], value)
+		}
+	}
+}
+
+func TestTwilioSendErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"twilio error", http.StatusBadRequest, `{"code": 21211, "message": "The 'To' number is not a valid phone number.", "more_info": "https://www.twilio.com/docs/errors/21211", "status": 400}`,
+			"twilio error 21211: The 'To' number is not a valid phone number."},
+		{"bad credentials", http.StatusUnauthorized, `{"code": 20003, "message": "Authenticate", "status": 401}`, "twilio error 20003: Authenticate"},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "twilio returned 502 Bad Gateway"},
+		{"json without a message", http.StatusInternalServerError, `{"status": 500}`, "twilio returned 500 Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newTwilioTestServer(t, tt.status, tt.body)
+
+			tw := Twilio{BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "token", From: "+15550001111"}
+
+			err := tw.Send("+15552223333", "hello")
+			if err == nil {
+				t.Fatal("send succeeded, want an error")
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("err = %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTwilioSendNotConfigured(t *testing.T) {
+	srv, received := newTwilioTestServer(t, http.StatusCreated, `{}`)
+
+	tests := []struct {
+		name    string
+		twilio  Twilio
+		to      string
+		wantErr string
+	}{
+		{"no account sid", Twilio{BaseURL: srv.URL, AuthToken: "token", From: "+15550001111"}, "+15552223333", "not configured"},
+		{"no auth token", Twilio{BaseURL: srv.URL, AccountSID: "AC123", From: "+15550001111"}, "+15552223333", "not configured"},
+		{"no from number", Twilio{BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "token"}, "+15552223333", "not configured"},
+		{"no to number", Twilio{BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "token", From: "+15550001111"}, "", "no number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.twilio.Send(tt.to, "hello")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if requests := received(); len(requests) != 0 {
+		t.Errorf("%d requests sent without a complete configuration", len(requests))
+	}
+}